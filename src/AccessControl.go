@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//roleAttribute - certificate attribute holding the role of the caller
+const roleAttribute = "role"
+
+//writerRoles - roles allowed to record mileage: certified workshops and the vehicle registry
+var writerRoles = []string{"workshop", "registry"}
+
+//readingRoles - roles allowed to submit readings, of the sources allowed by sourceRoles: certified workshops, the
+//vehicle registry, test station inspectors and telematics gateways
+var readingRoles = []string{"workshop", "registry", "inspector", "telematics"}
+
+//vehicleRoles - roles allowed to register vehicles and update their details: the vehicle registry
+var vehicleRoles = []string{"registry"}
+
+//deviceRoles - roles allowed to register telematics devices and their keys: the vehicle registry
+var deviceRoles = []string{"registry"}
+
+//replacementRoles - roles allowed to record an odometer replacement: certified workshops
+var replacementRoles = []string{"workshop"}
+
+//certificateRevokerRoles - roles allowed to revoke mileage certificates
+var certificateRevokerRoles = []string{"registry", "admin"}
+
+//adminRoles - roles allowed to archive all readings
+var adminRoles = []string{"admin"}
+
+//Submitter - identity of a caller, recorded with the changes it makes
+type Submitter struct {
+	MSPID   string `json:"mspID"`
+	Subject string `json:"subject"`
+}
+
+//Helper: Check the caller's MSP ID against the configured writer MSPs and its role attribute against roles. Without
+//writer MSPs every write is denied; a configuration that cannot be read is an internal error, not a denial.
+func (rdg *ReadingAsset) checkAccess(stub shim.ChaincodeStubInterface, roles ...string) error {
+	identity, err := cid.New(stub)
+	if err != nil {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Unable to identify caller")
+	}
+	mspID, err := identity.GetMSPID()
+	if err != nil {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Unable to get MSP ID of caller")
+	}
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return err
+	}
+	if len(config.WriterMSPIDs) == 0 {
+		return newError(UNAUTHORIZED, codeUnauthorized, "No writerMSPIDs configured - writes are denied")
+	}
+	if !containsString(config.WriterMSPIDs, mspID) {
+		return newError(UNAUTHORIZED, codeUnauthorized, "MSP "+mspID+" is not authorized")
+	}
+	role, found, err := identity.GetAttributeValue(roleAttribute)
+	if err != nil || !found {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Caller has no "+roleAttribute+" attribute")
+	}
+	if !containsString(roles, role) {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Role "+role+" is not authorized - requires one of: "+strings.Join(roles, ", "))
+	}
+	return nil
+}
+
+//Helper: Get MSP ID and certificate subject of the caller
+func getSubmitter(stub shim.ChaincodeStubInterface) (Submitter, error) {
+	var submitter Submitter
+	identity, err := cid.New(stub)
+	if err != nil {
+		return submitter, errors.New("Unable to identify caller")
+	}
+	submitter.MSPID, err = identity.GetMSPID()
+	if err != nil {
+		return submitter, errors.New("Unable to get MSP ID of caller")
+	}
+	certificate, err := identity.GetX509Certificate()
+	if err != nil || certificate == nil {
+		return submitter, errors.New("Unable to get certificate of caller")
+	}
+	submitter.Subject = certificate.Subject.String()
+	return submitter, nil
+}
+
+//containsString
+func containsString(array []string, key string) bool {
+	for _, entry := range array {
+		if entry == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestAccessControl_addNewReadingRoles
+func TestAccessControl_addNewReadingRoles(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkUnauthorized(t, stub, getFirstReadingAssetForTesting(), "addNewReading: Unable to identify caller")
+	stub.Creator = getCreatorForTesting("Org1MSP", "owner")
+	checkUnauthorized(t, stub, getFirstReadingAssetForTesting(),
+		"addNewReading: Role owner is not authorized - requires one of: workshop, registry, inspector, telematics")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+}
+
+//TestAccessControl_writerMSPIDs
+func TestAccessControl_writerMSPIDs(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte("{\"writerMSPIDs\":[\"Org1MSP\"]}")})
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org2MSP", "workshop")
+	checkUnauthorized(t, stub, getFirstReadingAssetForTesting(), "addNewReading: MSP Org2MSP is not authorized")
+	stub.Creator = getCreatorForTesting("Org2MSP", "admin")
+	checkUnauthorized(t, stub, getRemoveAllReadingAssetsForTesting(), "removeAllReadings: MSP Org2MSP is not authorized")
+	stub.Creator = getCreatorForTesting("Org2MSP", "workshop")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	stub.Creator = getCreatorForTesting("Org2MSP", "workshop")
+	checkUnauthorized(t, stub, getUpdateReadingAssetForOKTesting(), "updateReading: MSP Org2MSP is not authorized")
+	checkState(t, stub, "100001", getNewReadingExpected())
+}
+
+//TestAccessControl_defaultDeny
+func TestAccessControl_defaultDeny(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkUnauthorized(t, stub, getVehicleForTesting("registerVehicle", "100001", getVINForTesting("100001"), "US"),
+		"registerVehicle: No writerMSPIDs configured - writes are denied")
+	stub.State[getConfigKeyForTesting(stub)] = []byte("{")
+	res := stub.MockInvoke("1", getFirstReadingAssetForTesting())
+	checkErrorResponse(t, res.Status, res.Message, shim.ERROR, codeInternal,
+		"addNewReading: retrieveConfig: Corrupt configuration record {")
+}
+
+//checkUnauthorized - helper for checking an invoke is refused with status UNAUTHORIZED
+func checkUnauthorized(t *testing.T, stub *ExtendedMockStub, args [][]byte, expectedErr string) {
+	res := stub.MockInvoke("1", args)
+	if res.Status != UNAUTHORIZED {
+		fmt.Println("Invoke", string(args[0]), "expected status", UNAUTHORIZED, "Actual:", res.Status, res.Message)
+		t.FailNow()
+	}
+	checkError(t, expectedErr, res.Message)
+}
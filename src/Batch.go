@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//Modes of addReadingsBatch: atomic stores all readings or none, bestEffort stores every valid reading
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "bestEffort"
+)
+
+//maxBatchSize - most readings in one addReadingsBatch transaction, keeping the write set below the block size limit
+const maxBatchSize = 1000
+
+//ReadingsBatch - Input of addReadingsBatch: Readings of many vehicles, at most one per vehicle
+type ReadingsBatch struct {
+	Mode     string            `json:"mode"`
+	Readings []json.RawMessage `json:"readings"`
+}
+
+//BatchItemResult - Outcome of one Reading of a batch: the operation applied (add or update) and its status, with the
+//error envelope if the reading was not stored. A rejected update recorded as SuspiciousReading has status 200 and the
+//record in suspicious; it counts as rejected.
+type BatchItemResult struct {
+	Index      int             `json:"index"`
+	VehicleID  string          `json:"vehicleID,omitempty"`
+	Operation  string          `json:"operation,omitempty"`
+	Status     int32           `json:"status"`
+	Error      *ChaincodeError `json:"error,omitempty"`
+	Suspicious json.RawMessage `json:"suspicious,omitempty"`
+}
+
+//BatchResult - Outcome of addReadingsBatch, one result per Reading in input order
+type BatchResult struct {
+	Mode     string            `json:"mode"`
+	Stored   int               `json:"stored"`
+	Rejected int               `json:"rejected"`
+	Results  []BatchItemResult `json:"results"`
+}
+
+//BatchEvent - Payload of the event ReadingsBatchProcessed, replacing the events of the single reading routes
+type BatchEvent struct {
+	Mode      string    `json:"mode"`
+	Added     []string  `json:"added"`
+	Updated   []string  `json:"updated"`
+	Rejected  []string  `json:"rejected"`
+	Submitter Submitter `json:"submitter"`
+	TxID      string    `json:"txID"`
+}
+
+//batchSchema - schema of the ReadingsBatch JSON accepted by addReadingsBatch
+var batchSchema = []fieldSchema{
+	{name: "mode", validate: stringField(checkEnum(batchModeAtomic, batchModeBestEffort))},
+	{name: "readings", required: true, validate: checkBatchReadings},
+}
+
+//Invoke Route: addReadingsBatch - adds the first Reading or updates the Reading of each vehicle, with the rules of
+//addNewReading and updateReading. In atomic mode (default) a rejected Reading fails the transaction with
+//BATCH_REJECTED; in bestEffort mode the valid Readings are stored. Both return the result of every Reading. A ledger
+//error fails the whole transaction in both modes. A vehicle may appear once per batch, as a transaction does not read
+//its own writes. Each Reading may be a SignedReading envelope. The transient field locations may carry the GPS
+//positions of the vehicles by vehicle ID.
+func (rdg *ReadingAsset) addReadingsBatch(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, readingRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("addReadingsBatch: ", err))
+	}
+	batch, err := getBatchFromArgs(args)
+	if err != nil {
+		return validationFailed("addReadingsBatch: Batch Data is Corrupted", err)
+	}
+	locations, err := getLocationsFromTransient(stub)
+	if err != nil {
+		return validationFailed("addReadingsBatch: Location Data is Corrupted", err)
+	}
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return errorResponse(err)
+	}
+	result := BatchResult{Mode: batch.Mode, Results: make([]BatchItemResult, 0, len(batch.Readings))}
+	event := BatchEvent{Mode: batch.Mode, Added: []string{}, Updated: []string{}, Rejected: []string{}, TxID: stub.GetTxID()}
+	seen := make(map[string]bool)
+	for index, item := range batch.Readings {
+		itemResult := BatchItemResult{Index: index, Status: shim.OK}
+		reading, err := rdg.getReadingInput(stub, []string{string(item)})
+		if err == nil && seen[reading.VehicleID] {
+			itemResult.VehicleID = reading.VehicleID
+			err = newError(CONFLICT, codeDuplicateInBatch, "addReadingsBatch: More than one Reading for vehicle with ID: "+reading.VehicleID)
+		} else if err == nil {
+			seen[reading.VehicleID] = true
+			itemResult.VehicleID = reading.VehicleID
+			err = locateReading(&reading, locations[reading.VehicleID])
+			if err == nil {
+				itemResult.Operation, itemResult.Suspicious, err = rdg.storeBatchReading(stub, config, batch.Mode, reading)
+			}
+			if err == nil && itemResult.Suspicious == nil && locations[reading.VehicleID] != nil {
+				_, err = rdg.saveReadingLocation(stub, *locations[reading.VehicleID])
+			}
+		}
+		if err != nil {
+			rejection, ok := err.(ChaincodeError)
+			if !ok {
+				return errorResponse(withPrefix("addReadingsBatch: Reading "+strconv.Itoa(index)+": ", err))
+			}
+			itemResult.Status = rejection.Status
+			itemResult.Error = &rejection
+		}
+		if itemResult.Status == shim.OK && itemResult.Suspicious == nil {
+			result.Stored++
+			if itemResult.Operation == "add" {
+				event.Added = append(event.Added, reading.VehicleID)
+			} else {
+				event.Updated = append(event.Updated, reading.VehicleID)
+			}
+		} else {
+			result.Rejected++
+			if itemResult.VehicleID != "" {
+				event.Rejected = append(event.Rejected, itemResult.VehicleID)
+			}
+		}
+		result.Results = append(result.Results, itemResult)
+	}
+	if batch.Mode == batchModeAtomic && result.Rejected > 0 {
+		rejection := newError(CONFLICT, codeBatchRejected,
+			"addReadingsBatch: "+strconv.Itoa(result.Rejected)+" of "+strconv.Itoa(len(batch.Readings))+" Readings rejected - none stored")
+		rejection.Details = result
+		return errorResponse(rejection)
+	}
+	event.Submitter, err = getSubmitter(stub)
+	if err != nil {
+		return errorResponse(withPrefix("addReadingsBatch: ", err))
+	}
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		return internalError("addReadingsBatch: Error marshalling ReadingsBatchProcessed event JSON")
+	}
+	err = stub.SetEvent(eventReadingsBatch, bytes)
+	if err != nil {
+		return internalError("addReadingsBatch: Error setting ReadingsBatchProcessed event")
+	}
+	bytes, err = json.Marshal(result)
+	if err != nil {
+		return internalError("addReadingsBatch: Error marshalling batch result JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//Helper: Store batch reading - adds or updates the Reading of one vehicle, returns the operation applied. In bestEffort
+//mode a suspicious update is recorded if recordSuspiciousReadings is configured and its record returned instead.
+func (rdg *ReadingAsset) storeBatchReading(stub shim.ChaincodeStubInterface, config Config, mode string, reading Reading) (string, []byte, error) {
+	record, err := stub.GetState(reading.VehicleID)
+	if err != nil {
+		return "", nil, errors.New("storeBatchReading: Error retrieving reading with ID: " + reading.VehicleID)
+	}
+	if record == nil {
+		_, err = rdg.storeNewReading(stub, reading)
+		return "add", nil, err
+	}
+	currReading, newReading, err := rdg.storeReadingUpdate(stub, reading)
+	reason, rejected := rejectionReason(err)
+	if !rejected || mode != batchModeBestEffort || !config.RecordSuspiciousReadings {
+		return "update", nil, err
+	}
+	suspicious, err := rdg.recordSuspiciousReading(stub, currReading, newReading, reason, err.(ChaincodeError).Code)
+	return "update", suspicious, err
+}
+
+//checkBatchReadings - validator for the readings of a batch: an array of 1 to maxBatchSize JSON values, each
+//validated as Reading by addReadingsBatch
+func checkBatchReadings(value json.RawMessage) string {
+	var readings []json.RawMessage
+	if len(value) == 0 || value[0] != '[' || json.Unmarshal(value, &readings) != nil || len(readings) == 0 ||
+		len(readings) > maxBatchSize {
+		return "must be an array of 1 to " + strconv.Itoa(maxBatchSize) + " Readings"
+	}
+	return ""
+}
+
+//getBatchFromArgs - construct a batch structure from string array of arguments, in atomic mode unless stated
+func getBatchFromArgs(args []string) (batch ReadingsBatch, err error) {
+	if len(args) != 1 {
+		return batch, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one ReadingsBatch JSON argument"}}}
+	}
+	err = validateInput(args[0], batchSchema)
+	if err != nil {
+		return batch, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &batch)
+	if err != nil {
+		return batch, err
+	}
+	if batch.Mode == "" {
+		batch.Mode = batchModeAtomic
+	}
+	return batch, nil
+}
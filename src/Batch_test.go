@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestBatch_addReadingsBatchBestEffort
+func TestBatch_addReadingsBatchBestEffort(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getBatchForTesting("bestEffort",
+		string(getUpdateReadingAssetForOKTesting()[1]),
+		string(getSecondReadingAssetForTesting()[1]),
+		string(getUpdateReadingAssetForValueNOKTesting()[1]),
+		"{\"vehicleID\":\"100003\",\"docType\":\"Asset.Reading\",\"reading\":10,\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00+01:00\"}",
+		"{\"vehicleID\":\"100004\",\"docType\":\"Asset.Reading\",\"reading\":10,\"unit\":\"m\",\"creationDate\":\"2017-12-01T10:15:00+01:00\"}"))
+	if res.Status != shim.OK {
+		fmt.Println("func addReadingsBatch failed", res.Message)
+		t.FailNow()
+	}
+	var result BatchResult
+	err := json.Unmarshal(res.Payload, &result)
+	if err != nil || result.Stored != 2 || result.Rejected != 3 || len(result.Results) != 5 {
+		fmt.Println("func addReadingsBatch expected 2 stored and 3 rejected Readings, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	checkBatchItemResult(t, result.Results[0], "100001", "update", shim.OK, "")
+	checkBatchItemResult(t, result.Results[1], "100002", "add", shim.OK, "")
+	checkBatchItemResult(t, result.Results[2], "100001", "", CONFLICT, codeDuplicateInBatch)
+	checkBatchItemResult(t, result.Results[3], "100003", "add", NOTFOUND, codeVehicleNotRegistered)
+	checkBatchItemResult(t, result.Results[4], "", "", BADREQUEST, codeValidationFailed)
+	checkState(t, stub, "100001", getUpdatedReadingExpected())
+	checkReadingIDIndex(t, stub, []string{"100001", "100002"})
+	if len(stub.ChaincodeEventsChannel) != 1 {
+		fmt.Println("Expected exactly one ReadingsBatchProcessed event, Actual count:", len(stub.ChaincodeEventsChannel))
+		t.FailNow()
+	}
+	event := <-stub.ChaincodeEventsChannel
+	var actual BatchEvent
+	err = json.Unmarshal(event.Payload, &actual)
+	if event.EventName != eventReadingsBatch || err != nil || strings.Join(actual.Added, ",") != "100002" ||
+		strings.Join(actual.Updated, ",") != "100001" || strings.Join(actual.Rejected, ",") != "100001,100003" {
+		fmt.Println("Incorrect ReadingsBatchProcessed event:", event.EventName, string(event.Payload))
+		t.FailNow()
+	}
+}
+
+//TestBatch_addReadingsBatchAtomic
+func TestBatch_addReadingsBatchAtomic(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"recordSuspiciousReadings\":true"))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getBatchForTesting("",
+		string(getSecondReadingAssetForTesting()[1]),
+		string(getUpdateReadingAssetForValueNOKTesting()[1])))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeBatchRejected, "addReadingsBatch: 1 of 2 Readings rejected - none stored")
+	var envelope struct {
+		Details BatchResult `json:"details"`
+	}
+	err := json.Unmarshal([]byte(res.Message), &envelope)
+	if err != nil || envelope.Details.Mode != "atomic" || len(envelope.Details.Results) != 2 {
+		fmt.Println("func addReadingsBatch expected the results as details, Actual:", res.Message)
+		t.FailNow()
+	}
+	checkBatchItemResult(t, envelope.Details.Results[0], "100002", "add", shim.OK, "")
+	checkBatchItemResult(t, envelope.Details.Results[1], "100001", "update", CONFLICT, codeRollbackDetected)
+	res = stub.MockInvoke("1", [][]byte{[]byte("readSuspiciousReadings"), []byte("100001")})
+	if res.Status != NOTFOUND {
+		fmt.Println("func addReadingsBatch in atomic mode must not record suspicious readings, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+}
+
+//TestBatch_addReadingsBatchSuspicious
+func TestBatch_addReadingsBatchSuspicious(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"recordSuspiciousReadings\":true"))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getBatchForTesting("bestEffort", string(getUpdateReadingAssetForDateNOKTesting()[1])))
+	var result BatchResult
+	err := json.Unmarshal(res.Payload, &result)
+	if res.Status != shim.OK || err != nil || len(result.Results) != 1 {
+		fmt.Println("func addReadingsBatch failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkBatchItemResult(t, result.Results[0], "100001", "update", shim.OK, "")
+	var suspicious SuspiciousReading
+	err = json.Unmarshal(result.Results[0].Suspicious, &suspicious)
+	if err != nil || !suspicious.Rejected || suspicious.Code != codeDateRegression || result.Stored != 0 || result.Rejected != 1 {
+		fmt.Println("func addReadingsBatch expected the suspicious reading in the result, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("readSuspiciousReadings"), []byte("100001")})
+	var records []SuspiciousReading
+	err = json.Unmarshal(res.Payload, &records)
+	if err != nil || len(records) != 1 || records[0].Reason != "Date rollback" {
+		fmt.Println("func addReadingsBatch expected one suspicious reading, Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkState(t, stub, "100001", getNewReadingExpected())
+}
+
+//TestBatch_addReadingsBatchCorrupted
+func TestBatch_addReadingsBatchCorrupted(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	tests := map[string]string{
+		"{\"readings\":[]}":                     "readings: must be an array of 1 to 1000 Readings",
+		"{\"mode\":\"some\",\"readings\":[{}]}": "mode: must be one of: atomic, bestEffort",
+		"{\"mode\":\"atomic\"}":                 "readings: is required",
+	}
+	for input, expectedErr := range tests {
+		res := stub.MockInvoke("1", [][]byte{[]byte("addReadingsBatch"), []byte(input)})
+		checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed, "addReadingsBatch: Batch Data is Corrupted: "+expectedErr)
+	}
+	stub.Creator = getCreatorForTesting("Org1MSP", "owner")
+	checkUnauthorized(t, stub, getBatchForTesting("", string(getFirstReadingAssetForTesting()[1])),
+		"addReadingsBatch: Role owner is not authorized - requires one of: workshop, registry, inspector, telematics")
+}
+
+//checkBatchItemResult - helper for checking the result of one Reading of a batch
+func checkBatchItemResult(t *testing.T, result BatchItemResult, vehicleID string, operation string, status int32, code string) {
+	actualCode := ""
+	if result.Error != nil {
+		actualCode = result.Error.Code
+	}
+	if result.VehicleID != vehicleID || result.Operation != operation || result.Status != status || actualCode != code {
+		fmt.Println("Unexpected result of batch Reading", result.Index, "Expected:", vehicleID, operation, status, code,
+			"Actual:", result.VehicleID, result.Operation, result.Status, actualCode)
+		t.FailNow()
+	}
+}
+
+//Get addReadingsBatch arguments with the given mode ("" for the default) and Reading JSONs for testing
+func getBatchForTesting(mode string, readings ...string) [][]byte {
+	batch := "{\"readings\":[" + strings.Join(readings, ",") + "]}"
+	if mode != "" {
+		batch = "{\"mode\":\"" + mode + "\",\"readings\":[" + strings.Join(readings, ",") + "]}"
+	}
+	return [][]byte{[]byte("addReadingsBatch"), []byte(batch)}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//certificateKeyType - object type of the composite keys certificate~id of the mileage certificates
+const certificateKeyType = "certificate~id"
+
+//MileageCertificate - Current reading and history summary of a vehicle frozen at issuance, stored under the composite
+//key certificate~id. RecordsHash covers the supporting records, so the certificate can be verified against the ledger;
+//the endorsements of the issuing transaction txID sign it.
+type MileageCertificate struct {
+	ObjectType       string         `json:"docType"`
+	CertificateID    string         `json:"certificateID"`
+	VehicleID        string         `json:"vehicleID"`
+	VIN              string         `json:"vin"`
+	Reading          OdometerValue  `json:"reading"`
+	Unit             string         `json:"unit"`
+	Mileage          OdometerValue  `json:"mileage"`
+	ReadingDate      string         `json:"readingDate"`
+	Summary          HistorySummary `json:"summary"`
+	RecordsHash      string         `json:"recordsHash"`
+	IssuedBy         Submitter      `json:"issuedBy"`
+	TxID             string         `json:"txID"`
+	TxTimestamp      string         `json:"txTimestamp"`
+	Revoked          bool           `json:"revoked"`
+	RevokedBy        *Submitter     `json:"revokedBy,omitempty"`
+	RevocationReason string         `json:"revocationReason,omitempty"`
+	RevocationTxID   string         `json:"revocationTxID,omitempty"`
+}
+
+//HistorySummary - Summary of the reading history of a vehicle covered by a certificate
+type HistorySummary struct {
+	ReadingCount     int    `json:"readingCount"`
+	FirstReadingDate string `json:"firstReadingDate,omitempty"`
+	ReplacementCount int    `json:"replacementCount"`
+}
+
+//CertificateVerification - Result of verifyMileageCertificate: valid if the certificate is not revoked and the hash
+//of its supporting records still matches the ledger state
+type CertificateVerification struct {
+	CertificateID string             `json:"certificateID"`
+	Valid         bool               `json:"valid"`
+	Revoked       bool               `json:"revoked"`
+	HashMatches   bool               `json:"hashMatches"`
+	Certificate   MileageCertificate `json:"certificate"`
+}
+
+//revocationSchema - schema of the revocation JSON accepted by revokeMileageCertificate
+var revocationSchema = []fieldSchema{
+	{name: "certificateID", required: true, validate: stringField(checkID)},
+	{name: "reason", required: true, validate: stringField(checkText(256))},
+}
+
+//Invoke Route: issueMileageCertificate - argument: vehicle ID. Returns the certificate.
+func (rdg *ReadingAsset) issueMileageCertificate(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	var currReading Reading
+	err := rdg.checkAccess(stub, writerRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("issueMileageCertificate: ", err))
+	}
+	vehicle, err := rdg.retrieveVehicle(stub, vehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("issueMileageCertificate: ", err))
+	}
+	readingAsByteArray, err := rdg.retrieveReading(stub, vehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("issueMileageCertificate: ", err))
+	}
+	err = json.Unmarshal(readingAsByteArray, &currReading)
+	if err != nil {
+		return internalError("issueMileageCertificate: Error unmarshalling reading JSON")
+	}
+	history, err := retrieveRecordValues(stub, readingHistoryKeyType, vehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	replacements, err := retrieveRecordValues(stub, replacementKeyType, vehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	certificate := MileageCertificate{
+		ObjectType:    "Asset.MileageCertificate",
+		CertificateID: newCertificateID(stub.GetTxID(), vehicleID),
+		VehicleID:     vehicleID,
+		VIN:           vehicle.VIN,
+		Reading:       currReading.Reading,
+		Unit:          currReading.Unit,
+		Mileage:       OdometerValue(trueKilometres(currReading)),
+		ReadingDate:   currReading.CreationDate,
+		Summary:       HistorySummary{ReadingCount: len(history), ReplacementCount: len(replacements)},
+		RecordsHash:   hashCertificateRecords(vehicleID, vehicle.VIN, history, replacements),
+		TxID:          stub.GetTxID(),
+	}
+	if len(history) > 0 {
+		var first ReadingHistoryEntry
+		err = json.Unmarshal(history[0], &first)
+		if err != nil {
+			return internalError("issueMileageCertificate: Corrupt reading history record " + string(history[0]))
+		}
+		certificate.Summary.FirstReadingDate = first.Reading.CreationDate
+	}
+	certificate.IssuedBy, err = getSubmitter(stub)
+	if err != nil {
+		return errorResponse(withPrefix("issueMileageCertificate: ", err))
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return errorResponse(withPrefix("issueMileageCertificate: ", err))
+	}
+	certificate.TxTimestamp = txTime.Format(time.RFC3339Nano)
+	bytes, err := rdg.saveMileageCertificate(stub, certificate)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = stub.SetEvent(eventCertificateIssued, bytes)
+	if err != nil {
+		return internalError("issueMileageCertificate: Error setting MileageCertificateIssued event")
+	}
+	return shim.Success(bytes)
+}
+
+//Query Route: verifyMileageCertificate - recomputes the hash from the records the certificate covered: the first
+//readingCount history entries and replacementCount replacements, which are append-only
+func (rdg *ReadingAsset) verifyMileageCertificate(stub shim.ChaincodeStubInterface, certificateID string) peer.Response {
+	certificate, err := rdg.retrieveMileageCertificate(stub, certificateID)
+	if err != nil {
+		return errorResponse(withPrefix("verifyMileageCertificate: ", err))
+	}
+	verification := CertificateVerification{CertificateID: certificateID, Revoked: certificate.Revoked, Certificate: certificate}
+	vehicle, err := rdg.retrieveVehicle(stub, certificate.VehicleID)
+	if err == nil {
+		history, err := retrieveRecordValues(stub, readingHistoryKeyType, certificate.VehicleID)
+		if err != nil {
+			return errorResponse(err)
+		}
+		replacements, err := retrieveRecordValues(stub, replacementKeyType, certificate.VehicleID)
+		if err != nil {
+			return errorResponse(err)
+		}
+		if len(history) >= certificate.Summary.ReadingCount && len(replacements) >= certificate.Summary.ReplacementCount {
+			recordsHash := hashCertificateRecords(certificate.VehicleID, vehicle.VIN,
+				history[:certificate.Summary.ReadingCount], replacements[:certificate.Summary.ReplacementCount])
+			verification.HashMatches = recordsHash == certificate.RecordsHash
+		}
+	} else if chaincodeError, ok := err.(ChaincodeError); !ok || chaincodeError.Code != codeVehicleNotRegistered {
+		return errorResponse(err)
+	}
+	verification.Valid = verification.HashMatches && !verification.Revoked
+	bytes, err := json.Marshal(verification)
+	if err != nil {
+		return internalError("verifyMileageCertificate: Error marshalling certificate verification JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//Invoke Route: revokeMileageCertificate - argument: JSON with certificateID and reason
+func (rdg *ReadingAsset) revokeMileageCertificate(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	var revocation struct {
+		CertificateID string `json:"certificateID"`
+		Reason        string `json:"reason"`
+	}
+	err := rdg.checkAccess(stub, certificateRevokerRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("revokeMileageCertificate: ", err))
+	}
+	if len(args) != 1 {
+		return validationFailed("revokeMileageCertificate: Revocation Data is Corrupted",
+			ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one revocation JSON argument"}}})
+	}
+	err = validateInput(args[0], revocationSchema)
+	if err == nil {
+		err = json.Unmarshal([]byte(args[0]), &revocation)
+	}
+	if err != nil {
+		return validationFailed("revokeMileageCertificate: Revocation Data is Corrupted", err)
+	}
+	certificate, err := rdg.retrieveMileageCertificate(stub, revocation.CertificateID)
+	if err != nil {
+		return errorResponse(withPrefix("revokeMileageCertificate: ", err))
+	}
+	if certificate.Revoked {
+		return conflict(codeCertificateRevoked, "revokeMileageCertificate: Certificate "+certificate.CertificateID+" is already revoked")
+	}
+	submitter, err := getSubmitter(stub)
+	if err != nil {
+		return errorResponse(withPrefix("revokeMileageCertificate: ", err))
+	}
+	certificate.Revoked = true
+	certificate.RevokedBy = &submitter
+	certificate.RevocationReason = revocation.Reason
+	certificate.RevocationTxID = stub.GetTxID()
+	bytes, err := rdg.saveMileageCertificate(stub, certificate)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = stub.SetEvent(eventCertificateRevoked, bytes)
+	if err != nil {
+		return internalError("revokeMileageCertificate: Error setting MileageCertificateRevoked event")
+	}
+	return shim.Success(nil)
+}
+
+//Helper: Save mileage certificate - returns the stored JSON
+func (rdg *ReadingAsset) saveMileageCertificate(stub shim.ChaincodeStubInterface, certificate MileageCertificate) ([]byte, error) {
+	certificateKey, err := stub.CreateCompositeKey(certificateKeyType, []string{certificate.CertificateID})
+	if err != nil {
+		return nil, errors.New("saveMileageCertificate: Error creating certificate key for certificate with ID: " + certificate.CertificateID)
+	}
+	bytes, err := json.Marshal(certificate)
+	if err != nil {
+		return nil, errors.New("saveMileageCertificate: Error converting mileage certificate record JSON")
+	}
+	err = stub.PutState(certificateKey, bytes)
+	if err != nil {
+		return nil, errors.New("saveMileageCertificate: Error storing mileage certificate record")
+	}
+	return bytes, nil
+}
+
+//Helper: Retrieve mileage certificate - NOT_FOUND if no certificate was issued with the ID
+func (rdg *ReadingAsset) retrieveMileageCertificate(stub shim.ChaincodeStubInterface, certificateID string) (MileageCertificate, error) {
+	var certificate MileageCertificate
+	certificateKey, err := stub.CreateCompositeKey(certificateKeyType, []string{certificateID})
+	if err != nil {
+		return certificate, errors.New("retrieveMileageCertificate: Error creating certificate key for certificate with ID: " + certificateID)
+	}
+	bytes, err := stub.GetState(certificateKey)
+	if err != nil {
+		return certificate, errors.New("retrieveMileageCertificate: Error retrieving certificate with ID: " + certificateID)
+	}
+	if bytes == nil {
+		return certificate, newError(NOTFOUND, codeNotFound, "No certificate found with ID: "+certificateID)
+	}
+	err = json.Unmarshal(bytes, &certificate)
+	if err != nil {
+		return certificate, errors.New("retrieveMileageCertificate: Corrupt mileage certificate record " + string(bytes))
+	}
+	return certificate, nil
+}
+
+//newCertificateID - unique and the same on every endorsing peer: derived from the transaction ID and the vehicle ID
+func newCertificateID(txID string, vehicleID string) string {
+	hash := sha256.Sum256([]byte(txID + "\x00" + vehicleID))
+	return "MC-" + hex.EncodeToString(hash[:16])
+}
+
+//hashCertificateRecords - SHA-256 of the vehicle ID, the VIN and the records supporting a certificate as stored on the
+//ledger: every value is length prefixed and every record list counted, so no two sets of records hash alike
+func hashCertificateRecords(vehicleID string, vin string, recordLists ...[][]byte) string {
+	digest := sha256.New()
+	writeHashValue(digest, []byte(vehicleID))
+	writeHashValue(digest, []byte(vin))
+	for _, records := range recordLists {
+		writeHashLength(digest, len(records))
+		for _, record := range records {
+			writeHashValue(digest, record)
+		}
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+//writeHashValue - writes the length of value and value to digest
+func writeHashValue(digest hash.Hash, value []byte) {
+	writeHashLength(digest, len(value))
+	digest.Write(value)
+}
+
+//writeHashLength - writes length to digest as 8 byte big endian
+func writeHashLength(digest hash.Hash, length int) {
+	var prefix [8]byte
+	binary.BigEndian.PutUint64(prefix[:], uint64(length))
+	digest.Write(prefix[:])
+}
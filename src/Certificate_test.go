@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+//TestCertificate_issueAndVerify
+func TestCertificate_issueAndVerify(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", [][]byte{[]byte("issueMileageCertificate"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "issueMileageCertificate: retrieveReading: No reading found with ID: 100001")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "owner")
+	checkUnauthorized(t, stub, [][]byte{[]byte("issueMileageCertificate"), []byte("100001")},
+		"issueMileageCertificate: Role owner is not authorized - requires one of: workshop, registry")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	certificate := checkIssueMileageCertificate(t, stub, "100001")
+	if certificate.Mileage != 100 || certificate.Summary.ReadingCount != 2 || certificate.Summary.FirstReadingDate != "2017-12-01T09:15:00.000Z" ||
+		certificate.VIN != getVINForTesting("100001") || certificate.RecordsHash == "" {
+		fmt.Println("Unexpected mileage certificate", certificate)
+		t.FailNow()
+	}
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, true, true, false)
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":120,\"unit\":\"km\"", "2017-12-21T08:00:00Z"))
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, true, true, false)
+	checkInvoke(t, stub, getReplacementForTesting(130, 0, "2017-12-21T08:30:00Z"))
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, true, true, false)
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100003"), "US"))
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, false, false, false)
+}
+
+//TestCertificate_verifyStoredBytes
+func TestCertificate_verifyStoredBytes(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	certificate := checkIssueMileageCertificate(t, stub, "100001")
+	historyKey, _ := stub.CreateCompositeKey(readingHistoryKeyType, []string{"100001", "0000000001"})
+	stored := stub.State[historyKey]
+	if stored == nil {
+		fmt.Println("Expected reading history record", historyKey)
+		t.FailNow()
+	}
+	stub.State[historyKey] = append([]byte(" "), stored...)
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, false, false, false)
+	stub.State[historyKey] = stored
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, true, true, false)
+}
+
+//TestCertificate_revokeMileageCertificate
+func TestCertificate_revokeMileageCertificate(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	certificate := checkIssueMileageCertificate(t, stub, "100001")
+	revocation := [][]byte{[]byte("revokeMileageCertificate"),
+		[]byte("{\"certificateID\":\"" + certificate.CertificateID + "\",\"reason\":\"Issued for the wrong vehicle\"}")}
+	checkUnauthorized(t, stub, revocation, "revokeMileageCertificate: Role workshop is not authorized - requires one of: registry, admin")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, revocation)
+	checkVerifyMileageCertificate(t, stub, certificate.CertificateID, false, true, true)
+	res := stub.MockInvoke("1", revocation)
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeCertificateRevoked,
+		"revokeMileageCertificate: Certificate "+certificate.CertificateID+" is already revoked")
+	res = stub.MockInvoke("1", [][]byte{[]byte("verifyMileageCertificate"), []byte("MC-0")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "verifyMileageCertificate: No certificate found with ID: MC-0")
+}
+
+//checkIssueMileageCertificate - helper issuing a mileage certificate, checking the MileageCertificateIssued event
+func checkIssueMileageCertificate(t *testing.T, stub *ExtendedMockStub, vehicleID string) MileageCertificate {
+	var certificate MileageCertificate
+	res := stub.MockInvoke("1", [][]byte{[]byte("issueMileageCertificate"), []byte(vehicleID)})
+	err := json.Unmarshal(res.Payload, &certificate)
+	if res.Status != 200 || err != nil || certificate.CertificateID == "" || certificate.VehicleID != vehicleID {
+		fmt.Println("func issueMileageCertificate failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	event := <-stub.ChaincodeEventsChannel
+	if event.EventName != eventCertificateIssued || string(event.Payload) != string(res.Payload) {
+		fmt.Println("Expected MileageCertificateIssued event, Actual:", event.EventName, string(event.Payload))
+		t.FailNow()
+	}
+	return certificate
+}
+
+//checkVerifyMileageCertificate - helper for checking the result of verifyMileageCertificate
+func checkVerifyMileageCertificate(t *testing.T, stub *ExtendedMockStub, certificateID string, valid bool, hashMatches bool, revoked bool) {
+	var verification CertificateVerification
+	res := stub.MockInvoke("1", [][]byte{[]byte("verifyMileageCertificate"), []byte(certificateID)})
+	err := json.Unmarshal(res.Payload, &verification)
+	if res.Status != 200 || err != nil || verification.Valid != valid || verification.HashMatches != hashMatches || verification.Revoked != revoked {
+		fmt.Println("func verifyMileageCertificate expected valid:", valid, "hashMatches:", hashMatches, "revoked:", revoked,
+			"Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//configKeyType - object type of the composite key of the chaincode configuration; unlike a plain key it cannot clash
+//with the vehicle ID of a Reading
+const configKeyType = "config"
+
+//Config - Chaincode configuration, passed as JSON to Init at instantiation or upgrade and to updateConfig. The
+//confirmation token of removeAllReadings is not part of it: Init takes it from the transient field purgeConfirmation.
+//PrivateDataMSPIDs must match the member orgs of the collection policies in collections_config.json.
+//Telematics readings must be signed by their device unless AllowUnsignedTelematics is set; unsigned ones are then
+//accepted with medium confidence.
+type Config struct {
+	ObjectType               string                      `json:"docType"`
+	WriterMSPIDs             []string                    `json:"writerMSPIDs"`
+	PurgeConfirmation        string                      `json:"purgeConfirmation,omitempty"`
+	PurgeConfirmationHash    string                      `json:"purgeConfirmationHash,omitempty"`
+	MaxFutureSkew            string                      `json:"maxFutureSkew,omitempty"`
+	MaxBackdate              string                      `json:"maxBackdate,omitempty"`
+	Plausibility             map[string]PlausibilityRule `json:"plausibility,omitempty"`
+	RecordSuspiciousReadings bool                        `json:"recordSuspiciousReadings,omitempty"`
+	PrivateDataMSPIDs        []string                    `json:"privateDataMSPIDs,omitempty"`
+	AllowUnsignedTelematics  bool                        `json:"allowUnsignedTelematics,omitempty"`
+}
+
+//Defaults of the configuration
+const (
+	defaultMaxFutureSkew = 15 * time.Minute
+	defaultMaxBackdate   = 90 * 24 * time.Hour
+)
+
+//minConfirmationLength - shortest confirmation token accepted, so its hash kept in state cannot be reversed by guessing
+const minConfirmationLength = 16
+
+//Helper: Initialize configuration from the Init arguments - without arguments an existing configuration is kept, a
+//configuration JSON passed at upgrade is merged into it like in updateConfig. A confirmation token sent in the
+//transient field purgeConfirmation is set if none is stored.
+func (rdg *ReadingAsset) initConfig(stub shim.ChaincodeStubInterface, args []string) (bool, error) {
+	confirmation, err := getTransientField(stub, transientPurgeConfirmation)
+	if err != nil {
+		return false, withPrefix("initConfig: ", err)
+	}
+	input := "{}"
+	if len(args) > 0 && args[0] != "" {
+		input = args[0]
+	} else if confirmation == nil {
+		return false, nil
+	}
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return false, withPrefix("initConfig: ", err)
+	}
+	_, err = rdg.mergeConfig(stub, config, input, confirmation)
+	if err != nil {
+		return false, withPrefix("initConfig: ", err)
+	}
+	return true, nil
+}
+
+//Invoke Route: updateConfig - argument: configuration JSON; fields missing in it keep their value, plausibility
+//rules are replaced per vehicle category
+func (rdg *ReadingAsset) updateConfig(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, adminRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("updateConfig: ", err))
+	}
+	if len(args) != 1 {
+		return badRequest("updateConfig: Expects exactly one argument: configuration JSON")
+	}
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return errorResponse(withPrefix("updateConfig: ", err))
+	}
+	_, err = rdg.mergeConfig(stub, config, args[0], nil)
+	if err != nil {
+		return errorResponse(withPrefix("updateConfig: ", err))
+	}
+	return shim.Success(nil)
+}
+
+//Helper: Merge configuration JSON into config, check and save it - only the hash of the confirmation token is kept.
+//The token is only passed by Init and only set while none is stored, so an admin cannot replace it by updateConfig;
+//removeAllReadings clears it, so every purge needs a new one.
+func (rdg *ReadingAsset) mergeConfig(stub shim.ChaincodeStubInterface, config Config, input string, confirmation []byte) (bool, error) {
+	storedHash := config.PurgeConfirmationHash
+	err := json.Unmarshal([]byte(input), &config)
+	if err != nil {
+		return false, newError(BADREQUEST, codeBadRequest, "Configuration is not a valid JSON object")
+	}
+	if config.PurgeConfirmationHash != storedHash {
+		return false, newError(BADREQUEST, codeBadRequest, "purgeConfirmationHash is set by the chaincode and must not be sent")
+	}
+	if config.PurgeConfirmation != "" {
+		return false, newError(BADREQUEST, codeBadRequest, "purgeConfirmation must be sent to Init in the transient field "+
+			transientPurgeConfirmation+", not in the configuration")
+	}
+	if confirmation != nil && storedHash != "" {
+		return false, newError(BADREQUEST, codeBadRequest, "purgeConfirmation can only be set by Init while none is stored")
+	}
+	if confirmation != nil && len(confirmation) < minConfirmationLength {
+		return false, newError(BADREQUEST, codeBadRequest, "purgeConfirmation must be at least "+
+			strconv.Itoa(minConfirmationLength)+" characters")
+	}
+	_, _, err = config.readingTimeWindow()
+	if err != nil {
+		return false, newError(BADREQUEST, codeBadRequest, err.Error())
+	}
+	err = config.checkPlausibilityRules()
+	if err != nil {
+		return false, newError(BADREQUEST, codeBadRequest, err.Error())
+	}
+	if confirmation != nil {
+		config.PurgeConfirmationHash = hashConfirmation(string(confirmation))
+	}
+	return rdg.saveConfig(stub, config)
+}
+
+//Helper: Save configuration
+func (rdg *ReadingAsset) saveConfig(stub shim.ChaincodeStubInterface, config Config) (bool, error) {
+	config.ObjectType = "Config"
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return false, errors.New("saveConfig: Error converting configuration JSON")
+	}
+	configKey, err := stub.CreateCompositeKey(configKeyType, []string{})
+	if err != nil {
+		return false, errors.New("saveConfig: Error creating configuration key")
+	}
+	err = stub.PutState(configKey, bytes)
+	if err != nil {
+		return false, errors.New("saveConfig: Error storing configuration")
+	}
+	return true, nil
+}
+
+//Helper: Retrieve configuration - the defaults apply until a configuration is passed to Init
+func (rdg *ReadingAsset) retrieveConfig(stub shim.ChaincodeStubInterface) (Config, error) {
+	var config Config
+	configKey, err := stub.CreateCompositeKey(configKeyType, []string{})
+	if err != nil {
+		return config, errors.New("retrieveConfig: Error creating configuration key")
+	}
+	bytes, err := stub.GetState(configKey)
+	if err != nil {
+		return config, errors.New("retrieveConfig: Error retrieving configuration")
+	}
+	if bytes == nil {
+		return config, nil
+	}
+	err = json.Unmarshal(bytes, &config)
+	if err != nil {
+		return config, errors.New("retrieveConfig: Corrupt configuration record " + string(bytes))
+	}
+	return config, nil
+}
+
+//hashConfirmation - only the hash of a confirmation token is kept in state
+func hashConfirmation(confirmation string) string {
+	hash := sha256.Sum256([]byte(confirmation))
+	return hex.EncodeToString(hash[:])
+}
+
+//readingTimeWindow - how far a claimed reading time may lie after and before the transaction time
+func (config Config) readingTimeWindow() (maxFutureSkew time.Duration, maxBackdate time.Duration, err error) {
+	maxFutureSkew, maxBackdate = defaultMaxFutureSkew, defaultMaxBackdate
+	if config.MaxFutureSkew != "" {
+		maxFutureSkew, err = time.ParseDuration(config.MaxFutureSkew)
+		if err != nil || maxFutureSkew < 0 {
+			return maxFutureSkew, maxBackdate, errors.New("maxFutureSkew must be a non-negative duration, e.g. 15m")
+		}
+	}
+	if config.MaxBackdate != "" {
+		maxBackdate, err = time.ParseDuration(config.MaxBackdate)
+		if err != nil || maxBackdate < 0 {
+			return maxFutureSkew, maxBackdate, errors.New("maxBackdate must be a non-negative duration, e.g. 2160h")
+		}
+	}
+	return maxFutureSkew, maxBackdate, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestConfig_Init
+func TestConfig_Init(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte("{\"writerMSPIDs\":[\"Org1MSP\"]}")})
+	checkState(t, stub, getConfigKeyForTesting(stub), []byte("{\"docType\":\"Config\",\"writerMSPIDs\":[\"Org1MSP\"]}"))
+	checkInit(t, stub, [][]byte{[]byte("init")})
+	checkState(t, stub, getConfigKeyForTesting(stub), []byte("{\"docType\":\"Config\",\"writerMSPIDs\":[\"Org1MSP\"]}"))
+	res := stub.MockInit("1", [][]byte{[]byte("init"), []byte("writerMSPIDs")})
+	if res.Status == shim.OK {
+		fmt.Println("Init with invalid configuration was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "initConfig: Configuration is not a valid JSON object", res.Message)
+	checkInitWithPurgeConfirmation(t, stub)
+	checkState(t, stub, getConfigKeyForTesting(stub), []byte("{\"docType\":\"Config\",\"writerMSPIDs\":[\"Org1MSP\",\"Org2MSP\"],"+
+		"\"purgeConfirmationHash\":\""+hashConfirmation(purgeConfirmationForTesting)+"\"}"))
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte("{\"maxBackdate\":\"1h\"}")})
+	checkState(t, stub, getConfigKeyForTesting(stub), []byte("{\"docType\":\"Config\",\"writerMSPIDs\":[\"Org1MSP\",\"Org2MSP\"],"+
+		"\"purgeConfirmationHash\":\""+hashConfirmation(purgeConfirmationForTesting)+"\",\"maxBackdate\":\"1h\"}"))
+	res = initWithPurgeConfirmationForTesting(stub, [][]byte{[]byte("init")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"initConfig: purgeConfirmation can only be set by Init while none is stored")
+	res = stub.MockInit("1", getInitForTesting(",\"purgeConfirmation\":\"confirm-purge-0123456789\""))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"initConfig: purgeConfirmation must be sent to Init in the transient field purgeConfirmation, not in the configuration")
+	res = stub.MockInit("1", getInitForTesting(",\"maxBackdate\":\"90 days\""))
+	if res.Status == shim.OK {
+		fmt.Println("Init with invalid maxBackdate was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "initConfig: maxBackdate must be a non-negative duration, e.g. 2160h", res.Message)
+}
+
+//TestConfig_updateConfig
+func TestConfig_updateConfig(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte("{\"writerMSPIDs\":[\"Org1MSP\"]}")})
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkUnauthorized(t, stub, [][]byte{[]byte("updateConfig"), []byte("{\"maxBackdate\":\"1h\"}")},
+		"updateConfig: Role workshop is not authorized - requires one of: admin")
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkInvoke(t, stub, [][]byte{[]byte("updateConfig"), []byte("{\"maxBackdate\":\"1h\",\"plausibility\":{\"default\":{\"maxKmPerDay\":2000}}}")})
+	checkState(t, stub, getConfigKeyForTesting(stub), []byte("{\"docType\":\"Config\",\"writerMSPIDs\":[\"Org1MSP\"],\"maxBackdate\":\"1h\","+
+		"\"plausibility\":{\"default\":{\"maxKmPerDay\":2000}}}"))
+	res := stub.MockInvoke("1", [][]byte{[]byte("updateConfig"), []byte("{\"plausibility\":{\"truck\":{\"maxKmPerDay\":-1}}}")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"updateConfig: plausibility thresholds of category truck must not be negative")
+	res = stub.MockInvoke("1", [][]byte{[]byte("updateConfig"), []byte("{\"plausibility\":{\"truck\":{\"maxKmPerDay\":3000,\"onExceeded\":\"warn\"}}}")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"updateConfig: onExceeded of category truck must be one of: reject, flag")
+	res = stub.MockInvoke("1", [][]byte{[]byte("updateConfig"), []byte("{\"purgeConfirmation\":\"new-token\"}")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"updateConfig: purgeConfirmation must be sent to Init in the transient field purgeConfirmation, not in the configuration")
+	stub.Transient = map[string][]byte{transientPurgeConfirmation: []byte("short")}
+	res = stub.MockInit("1", [][]byte{[]byte("init")})
+	stub.Transient = nil
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest, "initConfig: purgeConfirmation must be at least 16 characters")
+	res = stub.MockInvoke("1", [][]byte{[]byte("updateConfig"), []byte("{\"purgeConfirmationHash\":\"" + hashConfirmation("new-token") + "\"}")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"updateConfig: purgeConfirmationHash is set by the chaincode and must not be sent")
+	checkInvoke(t, stub, [][]byte{[]byte("updateConfig"), []byte("{\"plausibility\":{\"truck\":{\"maxKmPerDay\":3000,\"onExceeded\":\"flag\"}}}")})
+	checkState(t, stub, getConfigKeyForTesting(stub), []byte("{\"docType\":\"Config\",\"writerMSPIDs\":[\"Org1MSP\"],\"maxBackdate\":\"1h\","+
+		"\"plausibility\":{\"default\":{\"maxKmPerDay\":2000},\"truck\":{\"maxKmPerDay\":3000,\"onExceeded\":\"flag\"}}}"))
+}
+
+//TestConfig_vehicleNamedConfig
+func TestConfig_vehicleNamedConfig(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	config := stub.State[getConfigKeyForTesting(stub)]
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getVehicleForTesting("registerVehicle", "config", getVINForTesting("100001"), "US"))
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "config", 50, "2017-12-01T10:15:00+01:00", ""))
+	checkState(t, stub, getConfigKeyForTesting(stub), config)
+	checkSourceOfReading(t, stub, "config", sourceWorkshop, "", confidenceHigh)
+}
+
+//Get the state key of the configuration for testing
+func getConfigKeyForTesting(stub *ExtendedMockStub) string {
+	configKey, _ := stub.CreateCompositeKey(configKeyType, []string{})
+	return configKey
+}
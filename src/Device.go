@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+	"golang.org/x/crypto/ed25519"
+)
+
+//deviceKeyType - object type of the composite keys device~deviceID of the telematics devices
+const deviceKeyType = "device~deviceID"
+
+//Signature algorithms of the device keys: ECDSA on P-256 over the SHA-256 of the payload with an ASN.1 DER signature,
+//or Ed25519 over the payload
+const (
+	algorithmECDSAP256 = "ECDSA-P256"
+	algorithmEd25519   = "Ed25519"
+)
+
+//oidEd25519 - algorithm identifier of Ed25519 keys (RFC 8410), parsed here as x509 of the Go releases in the Fabric
+//1.x images does not know it
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+//subjectPublicKeyInfo - ASN.1 structure of a DER encoded public key
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+//ecdsaSignature - ASN.1 structure of a DER encoded ECDSA signature, decoded here as ecdsa.VerifyASN1 needs Go 1.15
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+//Device - Details of the asset type Device, a telematics unit installed in a vehicle, stored under the composite key
+//device~deviceID. The device ID is its serial number; the public key verifies the readings it signs. The counter is
+//the highest counter of the signed readings accepted from the device, later readings must carry a greater one.
+type Device struct {
+	DeviceID         string `json:"deviceID"`
+	ObjectType       string `json:"docType"`
+	VehicleID        string `json:"vehicleID"`
+	PublicKey        string `json:"publicKey"`
+	Algorithm        string `json:"algorithm"`
+	Counter          uint64 `json:"counter"`
+	SubmitterMSPID   string `json:"submitterMSPID"`
+	SubmitterSubject string `json:"submitterSubject"`
+	TxID             string `json:"txID"`
+	TxTimestamp      string `json:"txTimestamp"`
+}
+
+//deviceSchema - schema of the Device JSON accepted by registerDevice and updateDevice
+var deviceSchema = []fieldSchema{
+	{name: "deviceID", required: true, validate: stringField(checkID)},
+	{name: "docType", required: true, validate: stringField(checkEnum("Asset.Device"))},
+	{name: "vehicleID", required: true, validate: stringField(checkID)},
+	{name: "publicKey", required: true, validate: stringField(checkPublicKey)},
+	{name: "algorithm", validate: readOnlyField},
+	{name: "counter", validate: readOnlyField},
+	{name: "submitterMSPID", validate: readOnlyField},
+	{name: "submitterSubject", validate: readOnlyField},
+	{name: "txID", validate: readOnlyField},
+	{name: "txTimestamp", validate: readOnlyField},
+}
+
+//SignedReading - Signed envelope of a Reading, accepted wherever a Reading JSON is: the payload is the base64 encoded
+//SignedPayload JSON as signed by the device, the signature its base64 encoded signature with the key of the device
+type SignedReading struct {
+	DeviceID  string `json:"deviceID"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+//signedReadingSchema - schema of the SignedReading JSON
+var signedReadingSchema = []fieldSchema{
+	{name: "deviceID", required: true, validate: stringField(checkID)},
+	{name: "payload", required: true, validate: stringField(checkBase64)},
+	{name: "signature", required: true, validate: stringField(checkBase64)},
+}
+
+//SignedPayload - Payload signed by a device: the Reading JSON with the counter of the device, which the device
+//increments for every reading it signs so that a signed payload cannot be replayed
+type SignedPayload struct {
+	Counter uint64          `json:"counter"`
+	Reading json.RawMessage `json:"reading"`
+}
+
+//signedPayloadSchema - schema of the SignedPayload JSON
+var signedPayloadSchema = []fieldSchema{
+	{name: "counter", required: true, validate: numberField(checkCounter)},
+	{name: "reading", required: true, validate: checkPayloadReading},
+}
+
+//Invoke Route: registerDevice - the vehicle of the device must be registered, a device ID can only be registered once
+func (rdg *ReadingAsset) registerDevice(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, deviceRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("registerDevice: ", err))
+	}
+	device, err := getDeviceFromArgs(args)
+	if err != nil {
+		return validationFailed("registerDevice: Device Data is Corrupted", err)
+	}
+	_, err = rdg.retrieveDevice(stub, device.DeviceID)
+	if err == nil {
+		return conflict(codeAlreadyExists, "registerDevice: This Device already exists: "+device.DeviceID)
+	}
+	if chaincodeError, ok := err.(ChaincodeError); !ok || chaincodeError.Code != codeNotFound {
+		return errorResponse(err)
+	}
+	return rdg.storeDevice(stub, device, "registerDevice: ")
+}
+
+//Invoke Route: updateDevice - replaces the key of a registered device, or moves it to another registered vehicle;
+//readings signed with the replaced key are no longer accepted. The counter of the device is kept.
+func (rdg *ReadingAsset) updateDevice(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, deviceRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("updateDevice: ", err))
+	}
+	device, err := getDeviceFromArgs(args)
+	if err != nil {
+		return validationFailed("updateDevice: Device Data is Corrupted", err)
+	}
+	currDevice, err := rdg.retrieveDevice(stub, device.DeviceID)
+	if err != nil {
+		return errorResponse(withPrefix("updateDevice: ", err))
+	}
+	device.Counter = currDevice.Counter
+	return rdg.storeDevice(stub, device, "updateDevice: ")
+}
+
+//Query Route: readDevice
+func (rdg *ReadingAsset) readDevice(stub shim.ChaincodeStubInterface, deviceID string) peer.Response {
+	device, err := rdg.retrieveDevice(stub, deviceID)
+	if err != nil {
+		return errorResponse(withPrefix("readDevice: ", err))
+	}
+	bytes, err := json.Marshal(device)
+	if err != nil {
+		return internalError("readDevice: Error marshalling device JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//storeDevice - checks the vehicle of device is registered, stamps and saves it; shared by registerDevice and
+//updateDevice, which prefix the errors with their name
+func (rdg *ReadingAsset) storeDevice(stub shim.ChaincodeStubInterface, device Device, prefix string) peer.Response {
+	_, err := rdg.retrieveVehicle(stub, device.VehicleID)
+	if err != nil {
+		return errorResponse(withPrefix(prefix, err))
+	}
+	err = stampDevice(stub, &device)
+	if err != nil {
+		return errorResponse(withPrefix(prefix, err))
+	}
+	_, err = rdg.saveDevice(stub, device)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+//Helper: Save device
+func (rdg *ReadingAsset) saveDevice(stub shim.ChaincodeStubInterface, device Device) (bool, error) {
+	deviceKey, err := stub.CreateCompositeKey(deviceKeyType, []string{device.DeviceID})
+	if err != nil {
+		return false, errors.New("saveDevice: Error creating device key for device with ID: " + device.DeviceID)
+	}
+	bytes, err := json.Marshal(device)
+	if err != nil {
+		return false, errors.New("saveDevice: Error converting device record JSON")
+	}
+	err = stub.PutState(deviceKey, bytes)
+	if err != nil {
+		return false, errors.New("saveDevice: Error storing device record")
+	}
+	return true, nil
+}
+
+//Helper: Retrieve device - NOT_FOUND if no device is registered with the ID
+func (rdg *ReadingAsset) retrieveDevice(stub shim.ChaincodeStubInterface, deviceID string) (Device, error) {
+	var device Device
+	deviceKey, err := stub.CreateCompositeKey(deviceKeyType, []string{deviceID})
+	if err != nil {
+		return device, errors.New("retrieveDevice: Error creating device key for device with ID: " + deviceID)
+	}
+	bytes, err := stub.GetState(deviceKey)
+	if err != nil {
+		return device, errors.New("retrieveDevice: Error retrieving device with ID: " + deviceID)
+	}
+	if bytes == nil {
+		return device, newError(NOTFOUND, codeNotFound, "No device registered with ID: "+deviceID)
+	}
+	err = json.Unmarshal(bytes, &device)
+	if err != nil {
+		return device, errors.New("retrieveDevice: Corrupt device record " + string(bytes))
+	}
+	return device, nil
+}
+
+//Helper: Save signed counter - records the counter of a signed reading on its device once the reading is stored, or
+//recorded as suspicious; readings not signed are ignored
+func (rdg *ReadingAsset) saveSignedCounter(stub shim.ChaincodeStubInterface, reading Reading) (bool, error) {
+	if reading.SignedBy == "" {
+		return false, nil
+	}
+	device, err := rdg.retrieveDevice(stub, reading.SignedBy)
+	if err != nil {
+		return false, withPrefix("saveSignedCounter: ", err)
+	}
+	device.Counter = reading.SignedCounter
+	return rdg.saveDevice(stub, device)
+}
+
+//Helper: Get the Reading of a SignedReading envelope - verifies the signature with the key of the registered device,
+//which must be installed in the vehicle of the reading, and that the counter of the payload is greater than the last
+//one accepted from the device. The reading becomes a telematics reading of the device.
+func (rdg *ReadingAsset) getSignedReading(stub shim.ChaincodeStubInterface, input string) (reading Reading, err error) {
+	err = validateInput(input, signedReadingSchema)
+	if err != nil {
+		return reading, newValidationError("Signed Reading is Corrupted", err)
+	}
+	var envelope SignedReading
+	err = json.Unmarshal([]byte(input), &envelope)
+	if err != nil {
+		return reading, newValidationError("Signed Reading is Corrupted", err)
+	}
+	payload, _ := base64.StdEncoding.DecodeString(envelope.Payload)
+	signature, _ := base64.StdEncoding.DecodeString(envelope.Signature)
+	device, err := rdg.retrieveDevice(stub, envelope.DeviceID)
+	if err != nil {
+		return reading, err
+	}
+	if !verifyDeviceSignature(device, payload, signature) {
+		return reading, newError(UNAUTHORIZED, codeSignatureRejected, "Signature does not match the key of device with ID: "+device.DeviceID)
+	}
+	err = validateInput(string(payload), signedPayloadSchema)
+	if err != nil {
+		return reading, newValidationError("Signed Reading is Corrupted", withFieldPrefix("payload.", err))
+	}
+	var signedPayload SignedPayload
+	err = json.Unmarshal(payload, &signedPayload)
+	if err != nil {
+		return reading, newValidationError("Signed Reading is Corrupted", err)
+	}
+	if signedPayload.Counter <= device.Counter {
+		return reading, newError(UNAUTHORIZED, codeSignatureRejected, "Counter "+strconv.FormatUint(signedPayload.Counter, 10)+
+			" of device "+device.DeviceID+" was already used - expects more than "+strconv.FormatUint(device.Counter, 10))
+	}
+	reading, err = getReadingFromArgs([]string{string(signedPayload.Reading)})
+	if err != nil {
+		return reading, newValidationError("Reading Data is Corrupted", withFieldPrefix("payload.reading.", err))
+	}
+	if reading.VehicleID != device.VehicleID {
+		return reading, newError(UNAUTHORIZED, codeSignatureRejected, "Device "+device.DeviceID+
+			" is not registered for vehicle with ID: "+reading.VehicleID)
+	}
+	if reading.Source != "" && reading.Source != sourceTelematics || reading.SourceRef != "" && reading.SourceRef != device.DeviceID {
+		return reading, newValidationError("Reading Data is Corrupted", ValidationError{Fields: []FieldError{{Field: "payload.reading",
+			Message: "signed readings must be telematics readings with the device ID as sourceRef"}}})
+	}
+	reading.Source = sourceTelematics
+	reading.SourceRef = device.DeviceID
+	reading.SignedBy = device.DeviceID
+	reading.SignedCounter = signedPayload.Counter
+	return reading, nil
+}
+
+//isSignedReading - whether the input JSON is a SignedReading envelope rather than a Reading
+func isSignedReading(input string) bool {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal([]byte(input), &fields)
+	if err != nil {
+		return false
+	}
+	_, signed := fields["signature"]
+	return signed
+}
+
+//verifyDeviceSignature - whether signature is a valid signature of payload with the public key of device
+func verifyDeviceSignature(device Device, payload []byte, signature []byte) bool {
+	publicKey, _, err := parseDeviceKey(device.PublicKey)
+	if err != nil {
+		return false
+	}
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		var sig ecdsaSignature
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+			return false
+		}
+		digest := sha256.Sum256(payload)
+		return ecdsa.Verify(key, digest[:], sig.R, sig.S)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	}
+	return false
+}
+
+//parseDeviceKey - the public key of a base64 encoded DER SubjectPublicKeyInfo and its algorithm; only ECDSA P-256
+//and Ed25519 keys are accepted
+func parseDeviceKey(value string) (interface{}, string, error) {
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, "", err
+	}
+	var info subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil || len(rest) != 0 {
+		return nil, "", errors.New("malformed SubjectPublicKeyInfo")
+	}
+	if info.Algorithm.Algorithm.Equal(oidEd25519) {
+		if len(info.Algorithm.Parameters.FullBytes) != 0 || info.PublicKey.BitLength != 8*ed25519.PublicKeySize ||
+			len(info.PublicKey.Bytes) != ed25519.PublicKeySize {
+			return nil, "", errors.New("malformed Ed25519 key")
+		}
+		return ed25519.PublicKey(info.PublicKey.Bytes), algorithmEd25519, nil
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, "", err
+	}
+	if key, ok := publicKey.(*ecdsa.PublicKey); ok && key.Curve == elliptic.P256() {
+		return key, algorithmECDSAP256, nil
+	}
+	return nil, "", errors.New("unsupported key type")
+}
+
+//checkPublicKey - device keys must be ECDSA P-256 or Ed25519 keys
+func checkPublicKey(value string) string {
+	_, _, err := parseDeviceKey(value)
+	if err != nil {
+		return "must be a base64 encoded DER SubjectPublicKeyInfo of an " + algorithmECDSAP256 + " or " + algorithmEd25519 + " key"
+	}
+	return ""
+}
+
+//checkPayloadReading - validator for the reading of a signed payload: a JSON object, validated as Reading by
+//getSignedReading
+func checkPayloadReading(value json.RawMessage) string {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return "must be a Reading JSON object"
+	}
+	return ""
+}
+
+//checkCounter - counters of signed readings are positive integers
+func checkCounter(value float64) string {
+	if value < 1 || value != math.Trunc(value) || value > 1<<53 {
+		return "must be a positive integer"
+	}
+	return ""
+}
+
+//stampDevice - records the submitting identity and the transaction on the device
+func stampDevice(stub shim.ChaincodeStubInterface, device *Device) error {
+	submitter, err := getSubmitter(stub)
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	device.SubmitterMSPID = submitter.MSPID
+	device.SubmitterSubject = submitter.Subject
+	device.TxID = stub.GetTxID()
+	device.TxTimestamp = txTime.Format(time.RFC3339Nano)
+	return nil
+}
+
+//getDeviceFromArgs - construct a device structure from string array of arguments, with the algorithm of its key
+func getDeviceFromArgs(args []string) (device Device, err error) {
+	if len(args) != 1 {
+		return device, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one Device JSON argument"}}}
+	}
+	err = validateInput(args[0], deviceSchema)
+	if err != nil {
+		return device, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &device)
+	if err != nil {
+		return device, err
+	}
+	_, device.Algorithm, err = parseDeviceKey(device.PublicKey)
+	return device, err
+}
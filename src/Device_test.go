@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"golang.org/x/crypto/ed25519"
+)
+
+//TestDevice_registerDevice
+func TestDevice_registerDevice(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001")
+	ecdsaKey := getECDSAKeyForTesting()
+	stub.Creator = getCreatorForTesting("Org1MSP", "telematics")
+	checkUnauthorized(t, stub, getDeviceForTesting("registerDevice", "TCU-4711", "100001", ecdsaKey.Public()),
+		"registerDevice: Role telematics is not authorized - requires one of: registry")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res := stub.MockInvoke("1", getDeviceForTesting("registerDevice", "TCU-4711", "100002", ecdsaKey.Public()))
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeVehicleNotRegistered, "registerDevice: No vehicle registered with ID: 100002")
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	res = stub.MockInvoke("1", getDeviceForTesting("registerDevice", "TCU-4711", "100001", p384Key.Public()))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"registerDevice: Device Data is Corrupted: publicKey: must be a base64 encoded DER SubjectPublicKeyInfo of an ECDSA-P256 or Ed25519 key")
+	checkInvoke(t, stub, getDeviceForTesting("registerDevice", "TCU-4711", "100001", ecdsaKey.Public()))
+	res = stub.MockInvoke("1", getDeviceForTesting("registerDevice", "TCU-4711", "100001", ecdsaKey.Public()))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeAlreadyExists, "registerDevice: This Device already exists: TCU-4711")
+	res = stub.MockInvoke("1", getDeviceForTesting("updateDevice", "TCU-4712", "100001", ecdsaKey.Public()))
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "updateDevice: No device registered with ID: TCU-4712")
+	checkDeviceAlgorithm(t, stub, "TCU-4711", algorithmECDSAP256)
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+	checkInvoke(t, stub, getDeviceForTesting("updateDevice", "TCU-4711", "100001", ed25519Key.Public()))
+	checkDeviceAlgorithm(t, stub, "TCU-4711", algorithmEd25519)
+	res = stub.MockInvoke("1", [][]byte{[]byte("readDevice"), []byte("TCU-4712")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "readDevice: No device registered with ID: TCU-4712")
+}
+
+//TestDevice_signedReadings
+func TestDevice_signedReadings(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	ecdsaKey := getECDSAKeyForTesting()
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getDeviceForTesting("registerDevice", "TCU-4711", "100001", ecdsaKey.Public()))
+	checkInvoke(t, stub, getDeviceForTesting("registerDevice", "TCU-4712", "100002", ed25519Key.Public()))
+	stub.Creator = getCreatorForTesting("Org1MSP", "telematics")
+	payload := string(getFirstReadingAssetForTesting()[1])
+	tampered := string(getUpdateReadingAssetForOKTesting()[1])
+	res := stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4711", 1, ecdsaKey, payload, tampered))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"Signature does not match the key of device with ID: TCU-4711")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4712", 1, ecdsaKey, payload, payload))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"Signature does not match the key of device with ID: TCU-4712")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4713", 1, ecdsaKey, payload, payload))
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "No device registered with ID: TCU-4713")
+	second := string(getSecondReadingAssetForTesting()[1])
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4711", 1, ecdsaKey, second, second))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"Device TCU-4711 is not registered for vehicle with ID: 100002")
+	manual := getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"source\":\"manual\"")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4711", 1, ecdsaKey, string(manual[1]), string(manual[1])))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Reading Data is Corrupted: payload.reading: signed readings must be telematics readings with the device ID as sourceRef")
+	unknown := string(getReadingAssetWithUnknownFieldForTesting()[1])
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4711", 1, ecdsaKey, unknown, unknown))
+	if res.Status != BADREQUEST {
+		fmt.Println("Signed reading with an unknown field expected status", BADREQUEST, "Actual:", res.Status, res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("addNewReading"), []byte("{\"deviceID\":\"TCU-4711\",\"payload\":\"e30=\",\"signature\":\"not base64\"}")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Signed Reading is Corrupted: signature: must be base64 encoded")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4711", 0, ecdsaKey, payload, payload))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Signed Reading is Corrupted: payload.counter: must be a positive integer")
+	checkInvoke(t, stub, getSignedReadingForTesting("addNewReading", "TCU-4711", 1, ecdsaKey, payload, payload))
+	checkSignedReading(t, stub, "100001", "TCU-4711", 1)
+	checkInvoke(t, stub, getSignedReadingForTesting("updateReading", "TCU-4711", 2, ecdsaKey, tampered, tampered))
+	checkSignedReading(t, stub, "100001", "TCU-4711", 2)
+	res = stub.MockInvoke("1", getSignedReadingForTesting("updateReading", "TCU-4711", 2, ecdsaKey, tampered, tampered))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"updateReading: Counter 2 of device TCU-4711 was already used - expects more than 2")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("updateReading", "TCU-4711", 1, ecdsaKey, payload, payload))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"updateReading: Counter 1 of device TCU-4711 was already used - expects more than 2")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("updateReading", "TCU-4712", 1, ed25519Key, payload, tampered))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"updateReading: Signature does not match the key of device with ID: TCU-4712")
+	stub.Creator = getCreatorForTesting("Org1MSP", "inspector")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4712", 1, ed25519Key, second, second))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeUnauthorized,
+		"addNewReading: Role inspector may not submit telematics readings - requires one of: telematics, workshop")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getSignedReadingForTesting("addNewReading", "TCU-4712", 1, ed25519Key, second, second))
+	checkSignedReading(t, stub, "100002", "TCU-4712", 1)
+}
+
+//TestDevice_updateDeviceKey
+func TestDevice_updateDeviceKey(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001")
+	oldKey := getECDSAKeyForTesting()
+	newKey := getECDSAKeyForTesting()
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getDeviceForTesting("registerDevice", "TCU-4711", "100001", oldKey.Public()))
+	checkInvoke(t, stub, getDeviceForTesting("updateDevice", "TCU-4711", "100001", newKey.Public()))
+	stub.Creator = getCreatorForTesting("Org1MSP", "telematics")
+	payload := string(getFirstReadingAssetForTesting()[1])
+	res := stub.MockInvoke("1", getSignedReadingForTesting("addNewReading", "TCU-4711", 1, oldKey, payload, payload))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"Signature does not match the key of device with ID: TCU-4711")
+	checkInvoke(t, stub, getSignedReadingForTesting("addNewReading", "TCU-4711", 1, newKey, payload, payload))
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getDeviceForTesting("updateDevice", "TCU-4711", "100001", newKey.Public()))
+	checkDeviceCounter(t, stub, "TCU-4711", 1)
+	stub.Creator = getCreatorForTesting("Org1MSP", "telematics")
+	res = stub.MockInvoke("1", getSignedReadingForTesting("updateReading", "TCU-4711", 1, newKey, payload, payload))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"updateReading: Counter 1 of device TCU-4711 was already used - expects more than 1")
+}
+
+//TestDevice_signedTelematics
+func TestDevice_signedTelematics(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	ecdsaKey := getECDSAKeyForTesting()
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getDeviceForTesting("registerDevice", "TCU-4711", "100001", ecdsaKey.Public()))
+	stub.Creator = getCreatorForTesting("Org1MSP", "telematics")
+	res := stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100002", 70, "2017-12-01T10:15:00+01:00", ",\"sourceRef\":\"TCU-4711\""))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"addNewReading: Telematics readings must be signed by their device")
+	payload := string(getFirstReadingAssetForTesting()[1])
+	checkInvoke(t, stub, getSignedReadingForTesting("addNewReading", "TCU-4711", 1, ecdsaKey, payload, payload))
+	res = stub.MockInvoke("1", getBatchForTesting("bestEffort",
+		string(getSignedReadingForTesting("", "TCU-4711", 2, ecdsaKey, payload, string(getUpdateReadingAssetForOKTesting()[1]))[1]),
+		string(getGeoReadingForTesting("", "100002", 70, "2017-12-01T10:15:00+01:00", ",\"sourceRef\":\"TCU-4711\"")[1])))
+	var result BatchResult
+	err := json.Unmarshal(res.Payload, &result)
+	if res.Status != shim.OK || err != nil || result.Stored != 0 || len(result.Results) != 2 {
+		fmt.Println("func addReadingsBatch expected 2 rejected Readings, Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkBatchItemResult(t, result.Results[0], "", "", UNAUTHORIZED, codeSignatureRejected)
+	checkBatchItemResult(t, result.Results[1], "100002", "add", UNAUTHORIZED, codeSignatureRejected)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getSecondReadingAssetForTesting())
+}
+
+//checkDeviceAlgorithm - helper checking the algorithm derived from the key of a registered device
+func checkDeviceAlgorithm(t *testing.T, stub *ExtendedMockStub, deviceID string, algorithm string) {
+	res := stub.MockInvoke("1", [][]byte{[]byte("readDevice"), []byte(deviceID)})
+	var device Device
+	err := json.Unmarshal(res.Payload, &device)
+	if err != nil || device.Algorithm != algorithm || device.SubmitterMSPID != "Org1MSP" {
+		fmt.Println("Device", deviceID, "expected algorithm", algorithm, "Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+}
+
+//checkDeviceCounter - helper checking the counter of the last signed reading accepted from a registered device
+func checkDeviceCounter(t *testing.T, stub *ExtendedMockStub, deviceID string, counter uint64) {
+	res := stub.MockInvoke("1", [][]byte{[]byte("readDevice"), []byte(deviceID)})
+	var device Device
+	err := json.Unmarshal(res.Payload, &device)
+	if err != nil || device.Counter != counter {
+		fmt.Println("Device", deviceID, "expected counter", counter, "Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+}
+
+//checkSignedReading - helper checking the current reading of a vehicle is a high confidence telematics reading signed
+//by the device with the counter, which the device records
+func checkSignedReading(t *testing.T, stub *ExtendedMockStub, vehicleID string, deviceID string, counter uint64) {
+	checkSourceOfReading(t, stub, vehicleID, sourceTelematics, deviceID, confidenceHigh)
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte(vehicleID)})
+	var reading Reading
+	err := json.Unmarshal(res.Payload, &reading)
+	if err != nil || reading.SignedBy != deviceID || reading.SignedCounter != counter {
+		fmt.Println("Reading of", vehicleID, "expected signed by", deviceID, "with counter", counter, "Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	checkDeviceCounter(t, stub, deviceID, counter)
+}
+
+//Get an ECDSA P-256 key for testing
+func getECDSAKeyForTesting() *ecdsa.PrivateKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return privateKey
+}
+
+//Get a Device JSON with the public key for testing; Ed25519 keys are marshalled like parseDeviceKey parses them
+func getDeviceForTesting(function string, deviceID string, vehicleID string, publicKey crypto.PublicKey) [][]byte {
+	var der []byte
+	var err error
+	if key, ok := publicKey.(ed25519.PublicKey); ok {
+		der, err = asn1.Marshal(subjectPublicKeyInfo{Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidEd25519},
+			PublicKey: asn1.BitString{Bytes: key, BitLength: 8 * len(key)}})
+	} else {
+		der, err = x509.MarshalPKIXPublicKey(publicKey)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return [][]byte{[]byte(function),
+		[]byte("{\"deviceID\":\"" + deviceID + "\",\"docType\":\"Asset.Device\",\"vehicleID\":\"" + vehicleID +
+			"\",\"publicKey\":\"" + base64.StdEncoding.EncodeToString(der) + "\"}")}
+}
+
+//Get a SignedReading envelope of payload with the signature of signed for testing, both Reading JSON sent with the
+//counter; they differ to test tampering
+func getSignedReadingForTesting(function string, deviceID string, counter uint64, privateKey crypto.Signer, signed string,
+	payload string) [][]byte {
+	prefix := "{\"counter\":" + strconv.FormatUint(counter, 10) + ",\"reading\":"
+	digest := []byte(prefix + signed + "}")
+	var opts crypto.SignerOpts = crypto.Hash(0)
+	if _, ok := privateKey.(*ecdsa.PrivateKey); ok {
+		hash := sha256.Sum256(digest)
+		digest, opts = hash[:], crypto.SHA256
+	}
+	signature, err := privateKey.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		panic(err)
+	}
+	envelope, _ := json.Marshal(SignedReading{DeviceID: deviceID, Payload: base64.StdEncoding.EncodeToString([]byte(prefix + payload + "}")),
+		Signature: base64.StdEncoding.EncodeToString(signature)})
+	return [][]byte{[]byte(function), envelope}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//Peer status codes of failed invokes and queries, in addition to shim.ERROR (500)
+const (
+	BADREQUEST   = 400
+	UNAUTHORIZED = 403
+	NOTFOUND     = 404
+	CONFLICT     = 409
+)
+
+//Catalogue of the error codes in the error envelope, with the peer status they are returned with
+const (
+	codeBadRequest           = "BAD_REQUEST"            //400: arguments missing or malformed
+	codeUnknownFunction      = "UNKNOWN_FUNCTION"       //400: no route with the invoked function name
+	codeValidationFailed     = "VALIDATION_FAILED"      //400: input JSON violates the schema, details lists every field
+	codeTimestampRejected    = "TIMESTAMP_REJECTED"     //400: reading time too far after or before the transaction time
+	codeUnauthorized         = "UNAUTHORIZED"           //403: MSP, role attribute or confirmation token not accepted
+	codeSignatureRejected    = "SIGNATURE_REJECTED"     //403: signed reading not verified by the device key or replayed, or telematics reading unsigned
+	codeNotFound             = "NOT_FOUND"              //404: the requested record does not exist
+	codeVehicleNotRegistered = "VEHICLE_NOT_REGISTERED" //404: no vehicle registered with the vehicle ID
+	codeAlreadyExists        = "ALREADY_EXISTS"         //409: a record with this ID already exists
+	codeRollbackDetected     = "ROLLBACK_DETECTED"      //409: new reading lower than the current reading
+	codeDateRegression       = "DATE_REGRESSION"        //409: new reading dated earlier than the current reading
+	codeImplausibleReading   = "IMPLAUSIBLE_READING"    //409: mileage growth above the maximum of the vehicle category
+	codeOwnerMismatch        = "OWNER_MISMATCH"         //409: the seller is not the current owner of the vehicle
+	codeSourceConflict       = "SOURCE_CONFLICT"        //409: the source of the reading may not override an earlier one
+	codeCertificateRevoked   = "CERTIFICATE_REVOKED"    //409: the mileage certificate is already revoked
+	codeDuplicateInBatch     = "DUPLICATE_IN_BATCH"     //409: a batch holds more than one reading of the vehicle
+	codeBatchRejected        = "BATCH_REJECTED"         //409: atomic batch with rejected readings, details lists all results
+	codeNotConfigured        = "NOT_CONFIGURED"         //409: the route needs configuration passed to Init
+	codeInternal             = "INTERNAL_ERROR"         //500: ledger access failed or a stored record is corrupt
+)
+
+//ChaincodeError - error envelope {code, message, details}, returned JSON encoded as message of the peer response
+type ChaincodeError struct {
+	Status  int32       `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+//Error - the message of the envelope
+func (err ChaincodeError) Error() string {
+	return err.Message
+}
+
+//newError - error with a peer status and a code of the catalogue, for helpers returning error
+func newError(status int32, code string, message string) ChaincodeError {
+	return ChaincodeError{Status: status, Code: code, Message: message}
+}
+
+//errorResponse - peer response for err; errors other than ChaincodeError are internal errors
+func errorResponse(err error) peer.Response {
+	chaincodeError, ok := err.(ChaincodeError)
+	if !ok {
+		chaincodeError = newError(shim.ERROR, codeInternal, err.Error())
+	}
+	bytes, marshalErr := json.Marshal(chaincodeError)
+	if marshalErr != nil {
+		return shim.Error(chaincodeError.Message)
+	}
+	return peer.Response{Status: chaincodeError.Status, Message: string(bytes)}
+}
+
+//badRequest - response for missing or malformed arguments
+func badRequest(message string) peer.Response {
+	return errorResponse(newError(BADREQUEST, codeBadRequest, message))
+}
+
+//validationFailed - response for input violating a schema; the offending fields of a ValidationError are the details
+func validationFailed(message string, err error) peer.Response {
+	return errorResponse(newValidationError(message, err))
+}
+
+//newValidationError - VALIDATION_FAILED error for helpers returning error, with the fields of a ValidationError as details
+func newValidationError(message string, err error) ChaincodeError {
+	chaincodeError := newError(BADREQUEST, codeValidationFailed, message+": "+err.Error())
+	if validationError, ok := err.(ValidationError); ok {
+		chaincodeError.Details = validationError.Fields
+	}
+	return chaincodeError
+}
+
+//unauthorized - response for callers failing the access check
+func unauthorized(message string) peer.Response {
+	return errorResponse(newError(UNAUTHORIZED, codeUnauthorized, message))
+}
+
+//notFound - response for requests of records that do not exist
+func notFound(message string) peer.Response {
+	return errorResponse(newError(NOTFOUND, codeNotFound, message))
+}
+
+//conflict - response for requests conflicting with the ledger state
+func conflict(code string, message string) peer.Response {
+	return errorResponse(newError(CONFLICT, code, message))
+}
+
+//internalError - response for failed ledger access and corrupt records
+func internalError(message string) peer.Response {
+	return errorResponse(newError(shim.ERROR, codeInternal, message))
+}
+
+//withPrefix - err with the route name prepended to its message, keeping code and status
+func withPrefix(prefix string, err error) error {
+	chaincodeError, ok := err.(ChaincodeError)
+	if !ok {
+		return errors.New(prefix + err.Error())
+	}
+	chaincodeError.Message = prefix + chaincodeError.Message
+	return chaincodeError
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+//TestErrors_errorResponse
+func TestErrors_errorResponse(t *testing.T) {
+	res := errorResponse(errors.New("Error storing Reading record"))
+	checkErrorResponse(t, res.Status, res.Message, 500, codeInternal, "Error storing Reading record")
+	res = errorResponse(withPrefix("addNewReading: ", newError(CONFLICT, codeAlreadyExists, "exists")))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeAlreadyExists, "addNewReading: exists")
+}
+
+//TestErrors_statusCodes
+func TestErrors_statusCodes(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReading")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest, "readReading: Expects exactly one argument: vehicle ID")
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "retrieveReading: No reading found with ID: 100001")
+	res = stub.MockInvoke("1", [][]byte{[]byte("removeReading")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeUnknownFunction, "Received unknown function invocation")
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2018-01-01T00:00:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeTimestampRejected,
+		"addNewReading: Reading time 2018-01-01T00:00:00.000Z is more than 15m0s after the transaction time 2017-12-21T09:00:00.000Z")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res = stub.MockInvoke("1", getFirstReadingAssetForTesting())
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeAlreadyExists, "This Reading already exists: 100001")
+	res = stub.MockInvoke("1", getUpdateReadingAssetForValueNOKTesting())
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeRollbackDetected,
+		"updateReading: New Reading is less than Current Reading - cannot update")
+	stub.Creator = getCreatorForTesting("Org1MSP", "owner")
+	res = stub.MockInvoke("1", getUpdateReadingAssetForOKTesting())
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeUnauthorized,
+		"updateReading: Role owner is not authorized - requires one of: workshop, registry, inspector, telematics")
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	res = removeAllReadingsForTesting(stub, "1")
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeNotConfigured,
+		"removeAllReadings: No confirmation token configured - Init sets a new one after each purge")
+}
+
+//TestErrors_validationDetails
+func TestErrors_validationDetails(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":-5,\"unit\":\"m\"", "2017-12-01T10:15:00+01:00"))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Reading Data is Corrupted: reading: must not be negative; unit: must be one of: km, mi")
+	var envelope struct {
+		Details []FieldError `json:"details"`
+	}
+	err := json.Unmarshal([]byte(res.Message), &envelope)
+	if err != nil || len(envelope.Details) != 2 || envelope.Details[0].Field != "reading" || envelope.Details[1].Field != "unit" {
+		fmt.Println("Validation error details expected for reading and unit, Actual:", res.Message)
+		t.FailNow()
+	}
+}
+
+//checkErrorResponse - helper for checking status, code and message of the error envelope
+func checkErrorResponse(t *testing.T, status int32, message string, expectedStatus int32, expectedCode string, expectedMessage string) {
+	var envelope ChaincodeError
+	err := json.Unmarshal([]byte(message), &envelope)
+	if err != nil || status != expectedStatus || envelope.Code != expectedCode || envelope.Message != expectedMessage {
+		fmt.Println("Expected:", expectedStatus, expectedCode, expectedMessage, "Actual:", status, message)
+		t.FailNow()
+	}
+}
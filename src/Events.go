@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Names of the chaincode events - Fabric delivers at most one event per transaction, set last by each route
+const (
+	eventReadingAdded         = "ReadingAdded"
+	eventReadingUpdated       = "ReadingUpdated"
+	eventReadingRejected      = "ReadingRejected"
+	eventReadingsPurged       = "ReadingsPurged"
+	eventReadingsBatch        = "ReadingsBatchProcessed"
+	eventOdometerReplaced     = "OdometerReplaced"
+	eventOwnershipTransferred = "OwnershipTransferred"
+	eventCertificateIssued    = "MileageCertificateIssued"
+	eventCertificateRevoked   = "MileageCertificateRevoked"
+)
+
+//ReadingEvent - Payload of the events ReadingAdded, ReadingUpdated, ReadingRejected and OdometerReplaced
+type ReadingEvent struct {
+	VehicleID string        `json:"vehicleID"`
+	OldValue  OdometerValue `json:"oldValue"`
+	OldUnit   string        `json:"oldUnit,omitempty"`
+	NewValue  OdometerValue `json:"newValue"`
+	NewUnit   string        `json:"newUnit"`
+	Reason    string        `json:"reason,omitempty"`
+	Submitter Submitter     `json:"submitter"`
+	TxID      string        `json:"txID"`
+}
+
+//setReadingEvent - sets the event name for a change of the reading of a vehicle from oldReading (empty if added)
+//to newReading. ReadingRejected is only set for rejections recorded as suspicious readings, whose transaction is
+//committed.
+func setReadingEvent(stub shim.ChaincodeStubInterface, name string, oldReading Reading, newReading Reading, reason string) error {
+	event := ReadingEvent{
+		VehicleID: newReading.VehicleID,
+		OldValue:  oldReading.Reading,
+		OldUnit:   oldReading.Unit,
+		NewValue:  newReading.Reading,
+		NewUnit:   newReading.Unit,
+		Reason:    reason,
+		TxID:      stub.GetTxID(),
+	}
+	submitter, err := getSubmitter(stub)
+	if err != nil {
+		return errors.New("setReadingEvent: " + err.Error())
+	}
+	event.Submitter = submitter
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		return errors.New("setReadingEvent: Error marshalling " + name + " event JSON")
+	}
+	err = stub.SetEvent(name, bytes)
+	if err != nil {
+		return errors.New("setReadingEvent: Error setting " + name + " event")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+//TestEvents_readingLifecycle
+func TestEvents_readingLifecycle(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkReadingEvent(t, stub, eventReadingAdded, ReadingEvent{VehicleID: "100001", NewValue: 50, NewUnit: "km"})
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	checkReadingEvent(t, stub, eventReadingUpdated, ReadingEvent{VehicleID: "100001", OldValue: 50, OldUnit: "km", NewValue: 100, NewUnit: "km"})
+}
+
+//TestEvents_readingRejected
+func TestEvents_readingRejected(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getUpdateReadingAssetForValueNOKTesting())
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeRollbackDetected,
+		"updateReading: New Reading is less than Current Reading - cannot update")
+	if len(stub.ChaincodeEventsChannel) != 0 {
+		fmt.Println("Expected no event for a rejection that is not recorded, Actual count:", len(stub.ChaincodeEventsChannel))
+		t.FailNow()
+	}
+	checkInit(t, stub, getInitForTesting(",\"recordSuspiciousReadings\":true"))
+	checkInvoke(t, stub, getUpdateReadingAssetForValueNOKTesting())
+	checkReadingEvent(t, stub, eventReadingRejected,
+		ReadingEvent{VehicleID: "100001", OldValue: 50, OldUnit: "km", NewValue: 20, NewUnit: "km", Reason: "Reading rollback"})
+	checkInvoke(t, stub, getUpdateReadingAssetForDateNOKTesting())
+	checkReadingEvent(t, stub, eventReadingRejected,
+		ReadingEvent{VehicleID: "100001", OldValue: 50, OldUnit: "km", NewValue: 100, NewUnit: "km", Reason: "Date rollback"})
+}
+
+//checkReadingEvent - helper for checking the single event set by the last invoke
+func checkReadingEvent(t *testing.T, stub *ExtendedMockStub, expectedName string, expected ReadingEvent) {
+	if len(stub.ChaincodeEventsChannel) != 1 {
+		fmt.Println("Expected exactly one", expectedName, "event, Actual count:", len(stub.ChaincodeEventsChannel))
+		t.FailNow()
+	}
+	event := <-stub.ChaincodeEventsChannel
+	var actual ReadingEvent
+	err := json.Unmarshal(event.Payload, &actual)
+	if event.EventName != expectedName || err != nil {
+		fmt.Println("Expected", expectedName, "event, Actual:", event.EventName, string(event.Payload))
+		t.FailNow()
+	}
+	expected.Submitter = Submitter{MSPID: "Org1MSP", Subject: "CN=workshop@Org1MSP,O=Org1MSP"}
+	expected.TxID = "1"
+	if actual != expected {
+		fmt.Println("Incorrect", expectedName, "event: \nExpected:", expected, "\nActual  :", actual)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//geohashKeyType - object type of the composite keys indexing every accepted Reading with a location by the characters
+//of its geohash, then vehicle ID and sequence number of its vehicle~seq history entry. One attribute per character lets
+//a partial key select any geohash cell. The index holds no Reading, only the key pointing to its history entry.
+const geohashKeyType = "geo~hash~vehicle~seq"
+
+//geohashAlphabet - base 32 alphabet of geohashes
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+//geohashPrecision - characters of the public geohash stored with a Reading, a cell of about 5 x 5 km: coarse enough
+//not to reveal where the vehicle was parked, whose exact position stays in collectionReadingLocations
+const geohashPrecision = 5
+
+//maxGeohashCells - most geohash cells scanned to cover the bounding box of queryReadingsNear
+const maxGeohashCells = 16
+
+//NearQuery - Input of queryReadingsNear: a geohash cell or a bounding box, and an optional date range and source.
+//Bounding boxes crossing the antimeridian are not supported: query the boxes on both sides of it.
+type NearQuery struct {
+	Geohash      string   `json:"geohash"`
+	MinLatitude  *float64 `json:"minLatitude"`
+	MinLongitude *float64 `json:"minLongitude"`
+	MaxLatitude  *float64 `json:"maxLatitude"`
+	MaxLongitude *float64 `json:"maxLongitude"`
+	From         string   `json:"from"`
+	To           string   `json:"to"`
+	Source       string   `json:"source"`
+}
+
+//nearQuerySchema - schema of the NearQuery JSON accepted by queryReadingsNear
+var nearQuerySchema = []fieldSchema{
+	{name: "geohash", validate: stringField(checkGeohash)},
+	{name: "minLatitude", validate: numberField(checkRange(-90, 90))},
+	{name: "minLongitude", validate: numberField(checkRange(-180, 180))},
+	{name: "maxLatitude", validate: numberField(checkRange(-90, 90))},
+	{name: "maxLongitude", validate: numberField(checkRange(-180, 180))},
+	{name: "from", validate: stringField(checkTimestamp)},
+	{name: "to", validate: stringField(checkTimestamp)},
+	{name: "source", validate: stringField(checkEnum(sources...))},
+}
+
+//Query Route: queryReadingsNear - arguments: NearQuery JSON, optional page size and bookmark returned by the previous
+//page. One page of the accepted Readings, current and past, whose geohash cell lies in the geohash cell or intersects
+//the bounding box, taken within the date range and of the source, in geohash order. The page size bounds the index
+//entries scanned, so a page may hold fewer Readings; the bookmark is "" on the last page.
+func (rdg *ReadingAsset) queryReadingsNear(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 {
+		return badRequest("queryReadingsNear: Missing query")
+	}
+	query, err := getNearQueryFromArgs(args[:1])
+	if err != nil {
+		return validationFailed("queryReadingsNear: Query is Corrupted", err)
+	}
+	pageSize, bookmark, err := getPaginationFromArgs(args[1:])
+	if err != nil {
+		return badRequest("queryReadingsNear: " + err.Error())
+	}
+	cells := []string{query.Geohash}
+	if query.Geohash == "" {
+		cells = geohashCells(*query.MinLatitude, *query.MinLongitude, *query.MaxLatitude, *query.MaxLongitude)
+		sort.Strings(cells)
+	}
+	first, cellBookmark, err := splitNearBookmark(cells, bookmark)
+	if err != nil {
+		return badRequest("queryReadingsNear: " + err.Error())
+	}
+	var from, to time.Time
+	if query.From != "" {
+		from, _ = parseReadingTime(query.From)
+	}
+	if query.To != "" {
+		to, _ = parseReadingTime(query.To)
+	}
+	page := ReadingPage{Records: []Reading{}}
+	for i := first; i < len(cells) && page.Bookmark == ""; i++ {
+		if page.FetchedCount == pageSize {
+			page.Bookmark = cells[i] + ":"
+			break
+		}
+		iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(geohashKeyType, strings.Split(cells[i], ""),
+			pageSize-page.FetchedCount, cellBookmark)
+		if err != nil {
+			return internalError("queryReadingsNear: Error retrieving geohash index for cell " + cells[i])
+		}
+		for iterator.HasNext() {
+			kv, err := iterator.Next()
+			if err != nil {
+				iterator.Close()
+				return internalError("queryReadingsNear: Error iterating geohash index for cell " + cells[i])
+			}
+			page.FetchedCount++
+			reading, err := rdg.retrieveIndexedReading(stub, kv.Key)
+			if err != nil {
+				iterator.Close()
+				return errorResponse(withPrefix("queryReadingsNear: ", err))
+			}
+			if query.matches(reading, from, to) {
+				page.Records = append(page.Records, reading)
+			}
+		}
+		iterator.Close()
+		if metadata.Bookmark != "" {
+			page.Bookmark = cells[i] + ":" + metadata.Bookmark
+		}
+		cellBookmark = ""
+	}
+	bytes, err := json.Marshal(page)
+	if err != nil {
+		return internalError("queryReadingsNear: Error marshalling reading page JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//splitNearBookmark - index of the cell to continue with and the bookmark within it, from a bookmark of
+//queryReadingsNear: the cell and the bookmark of its index scan, separated by a colon
+func splitNearBookmark(cells []string, bookmark string) (int, string, error) {
+	if bookmark == "" {
+		return 0, "", nil
+	}
+	parts := strings.SplitN(bookmark, ":", 2)
+	for i, cell := range cells {
+		if len(parts) == 2 && parts[0] == cell {
+			return i, parts[1], nil
+		}
+	}
+	return 0, "", errors.New("Bookmark does not belong to this query")
+}
+
+//Helper: Retrieve indexed reading - the Reading of the vehicle~seq history entry a geohash index key points to
+func (rdg *ReadingAsset) retrieveIndexedReading(stub shim.ChaincodeStubInterface, indexKey string) (Reading, error) {
+	var entry ReadingHistoryEntry
+	_, keyParts, err := stub.SplitCompositeKey(indexKey)
+	if err != nil || len(keyParts) != geohashPrecision+2 {
+		return entry.Reading, errors.New("retrieveIndexedReading: Corrupt geohash index key " + indexKey)
+	}
+	historyKey, err := stub.CreateCompositeKey(readingHistoryKeyType, keyParts[geohashPrecision:])
+	if err != nil {
+		return entry.Reading, errors.New("retrieveIndexedReading: Error creating history key for geohash index key " + indexKey)
+	}
+	bytes, err := stub.GetState(historyKey)
+	if err != nil || bytes == nil {
+		return entry.Reading, errors.New("retrieveIndexedReading: Error retrieving reading history record of geohash index key " + indexKey)
+	}
+	err = json.Unmarshal(bytes, &entry)
+	if err != nil {
+		return entry.Reading, errors.New("retrieveIndexedReading: Corrupt reading history record " + string(bytes))
+	}
+	return entry.Reading, nil
+}
+
+//matches - whether the geohash cell of reading intersects the bounding box (if any), the reading is of the source (if
+//set) and was taken between from and to (if set)
+func (query NearQuery) matches(reading Reading, from time.Time, to time.Time) bool {
+	if query.Source != "" && reading.Source != query.Source {
+		return false
+	}
+	if query.Geohash == "" {
+		minLatitude, minLongitude, maxLatitude, maxLongitude := geohashBounds(reading.Geohash)
+		if maxLatitude < *query.MinLatitude || minLatitude > *query.MaxLatitude ||
+			maxLongitude < *query.MinLongitude || minLongitude > *query.MaxLongitude {
+			return false
+		}
+	}
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	readingTime, err := parseReadingTime(reading.CreationDate)
+	if err != nil {
+		return false
+	}
+	return !readingTime.Before(from) && (to.IsZero() || !readingTime.After(to))
+}
+
+//Helper: Update geohash index - one composite key per accepted Reading with a location, pointing to its history
+//entry seq. The value is a placeholder, so rich queries over the state never see the index.
+func (rdg *ReadingAsset) updateGeohashIndex(stub shim.ChaincodeStubInterface, reading Reading, seq int) (bool, error) {
+	if reading.Geohash == "" {
+		return false, nil
+	}
+	attributes := append(strings.Split(reading.Geohash, ""), reading.VehicleID, formatSequence(seq))
+	indexKey, err := stub.CreateCompositeKey(geohashKeyType, attributes)
+	if err != nil {
+		return false, errors.New("updateGeohashIndex: Error creating geohash index key for reading ID: " + reading.VehicleID)
+	}
+	err = stub.PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return false, errors.New("updateGeohashIndex: Error storing reading in geohash index")
+	}
+	return true, nil
+}
+
+//Helper: Delete geohash index - removes the index entries of every Reading
+func (rdg *ReadingAsset) deleteGeohashIndex(stub shim.ChaincodeStubInterface) (bool, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(geohashKeyType, []string{})
+	if err != nil {
+		return false, errors.New("deleteGeohashIndex: Error retrieving geohash index")
+	}
+	indexKeys := []string{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			iterator.Close()
+			return false, errors.New("deleteGeohashIndex: Error iterating geohash index")
+		}
+		indexKeys = append(indexKeys, kv.Key)
+	}
+	iterator.Close()
+	for _, indexKey := range indexKeys {
+		err = stub.DelState(indexKey)
+		if err != nil {
+			return false, errors.New("deleteGeohashIndex: Error deleting geohash index key " + indexKey)
+		}
+	}
+	return true, nil
+}
+
+//locateReading - sets the location hash of reading and its public geohash, coarsened to geohashPrecision, from the
+//private location of the reading; neither is set without location
+func locateReading(reading *Reading, location *ReadingLocation) error {
+	hash, err := locationHash(location)
+	if err != nil {
+		return err
+	}
+	reading.LocationHash = hash
+	if location != nil {
+		reading.Geohash = encodeGeohash(location.Latitude, location.Longitude, geohashPrecision)
+	}
+	return nil
+}
+
+//encodeGeohash - geohash of the cell of precision characters containing the position
+func encodeGeohash(latitude float64, longitude float64, precision int) string {
+	lonBits, latBits := geohashBits(precision)
+	return geohashFromCell(geohashCellIndex(longitude, -180, 360, lonBits), geohashCellIndex(latitude, -90, 180, latBits), precision)
+}
+
+//geohashCells - the geohash cells covering the bounding box, of the finest precision needing at most maxGeohashCells
+func geohashCells(minLatitude float64, minLongitude float64, maxLatitude float64, maxLongitude float64) []string {
+	for precision := geohashPrecision; precision > 1; precision-- {
+		lonBits, latBits := geohashBits(precision)
+		minX, maxX := geohashCellIndex(minLongitude, -180, 360, lonBits), geohashCellIndex(maxLongitude, -180, 360, lonBits)
+		minY, maxY := geohashCellIndex(minLatitude, -90, 180, latBits), geohashCellIndex(maxLatitude, -90, 180, latBits)
+		if (maxX-minX+1)*(maxY-minY+1) > maxGeohashCells {
+			continue
+		}
+		cells := make([]string, 0, maxGeohashCells)
+		for x := minX; x <= maxX; x++ {
+			for y := minY; y <= maxY; y++ {
+				cells = append(cells, geohashFromCell(x, y, precision))
+			}
+		}
+		return cells
+	}
+	return strings.Split(geohashAlphabet, "")
+}
+
+//geohashBounds - latitude and longitude bounds of the cell of a geohash
+func geohashBounds(geohash string) (minLatitude float64, minLongitude float64, maxLatitude float64, maxLongitude float64) {
+	x, y := 0, 0
+	for i := 0; i < 5*len(geohash); i++ {
+		bit := strings.IndexByte(geohashAlphabet, geohash[i/5]) >> uint(4-i%5) & 1
+		if i%2 == 0 {
+			x = x<<1 | bit
+		} else {
+			y = y<<1 | bit
+		}
+	}
+	lonBits, latBits := geohashBits(len(geohash))
+	lonSpan, latSpan := 360/float64(int(1)<<lonBits), 180/float64(int(1)<<latBits)
+	return -90 + float64(y)*latSpan, -180 + float64(x)*lonSpan, -90 + float64(y+1)*latSpan, -180 + float64(x+1)*lonSpan
+}
+
+//geohashBits - bits of longitude and latitude in a geohash of precision characters; longitude takes the odd bit
+func geohashBits(precision int) (lonBits uint, latBits uint) {
+	bits := uint(5 * precision)
+	return (bits + 1) / 2, bits / 2
+}
+
+//geohashCellIndex - index of the cell containing value among the 2^bits cells dividing [min, min+span]
+func geohashCellIndex(value float64, min float64, span float64, bits uint) int {
+	cells := 1 << bits
+	index := int(math.Floor((value - min) / span * float64(cells)))
+	if index >= cells {
+		index = cells - 1
+	}
+	return index
+}
+
+//geohashFromCell - geohash of precision characters interleaving the bits of the longitude and latitude cell indexes
+func geohashFromCell(x int, y int, precision int) string {
+	lonBits, latBits := geohashBits(precision)
+	geohash := make([]byte, precision)
+	for i := 0; i < 5*precision; i++ {
+		var bit int
+		if i%2 == 0 {
+			lonBits--
+			bit = (x >> lonBits) & 1
+		} else {
+			latBits--
+			bit = (y >> latBits) & 1
+		}
+		geohash[i/5] = geohash[i/5]<<1 | byte(bit)
+	}
+	for i := range geohash {
+		geohash[i] = geohashAlphabet[geohash[i]]
+	}
+	return string(geohash)
+}
+
+//checkGeohash - geohash cells of 1 to geohashPrecision characters
+func checkGeohash(value string) string {
+	if value == "" || len(value) > geohashPrecision || strings.Trim(value, geohashAlphabet) != "" {
+		return "must be a geohash of 1 to " + strconv.Itoa(geohashPrecision) + " characters of " + geohashAlphabet
+	}
+	return ""
+}
+
+//getNearQueryFromArgs - construct a near query structure from string array of arguments: either a geohash or all
+//four bounds of a bounding box
+func getNearQueryFromArgs(args []string) (query NearQuery, err error) {
+	if len(args) != 1 {
+		return query, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one NearQuery JSON argument"}}}
+	}
+	err = validateInput(args[0], nearQuerySchema)
+	if err != nil {
+		return query, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &query)
+	if err != nil {
+		return query, err
+	}
+	bounds := query.MinLatitude != nil && query.MinLongitude != nil && query.MaxLatitude != nil && query.MaxLongitude != nil
+	anyBound := query.MinLatitude != nil || query.MinLongitude != nil || query.MaxLatitude != nil || query.MaxLongitude != nil
+	if query.Geohash != "" && anyBound || query.Geohash == "" && !bounds {
+		return query, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects either geohash or minLatitude, minLongitude, maxLatitude and maxLongitude"}}}
+	}
+	if bounds && (*query.MinLatitude > *query.MaxLatitude || *query.MinLongitude > *query.MaxLongitude) {
+		return query, ValidationError{Fields: []FieldError{{Field: "$", Message: "minimum bounds must not exceed maximum bounds - " +
+			"query boxes crossing the antimeridian as two boxes"}}}
+	}
+	return query, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestGeohash_encodeGeohash
+func TestGeohash_encodeGeohash(t *testing.T) {
+	tests := []struct {
+		latitude  float64
+		longitude float64
+		precision int
+		geohash   string
+	}{
+		{57.64911, 10.40744, 9, "u4pruydqq"},
+		{42.6, -5.6, 5, "ezs42"},
+		{-90, -180, 3, "000"},
+		{90, 180, 3, "zzz"},
+	}
+	for _, test := range tests {
+		geohash := encodeGeohash(test.latitude, test.longitude, test.precision)
+		if geohash != test.geohash {
+			fmt.Println("encodeGeohash of", test.latitude, test.longitude, "Expected:", test.geohash, "Actual:", geohash)
+			t.FailNow()
+		}
+	}
+}
+
+//TestGeohash_geohashCells
+func TestGeohash_geohashCells(t *testing.T) {
+	cells := geohashCells(57.64911, 10.40744, 57.64911, 10.40744)
+	if len(cells) != 1 || cells[0] != "u4pru" {
+		fmt.Println("geohashCells of a point expected its cell, Actual:", cells)
+		t.FailNow()
+	}
+	cells = geohashCells(49, 8, 50, 9)
+	if len(cells) == 0 || len(cells) > maxGeohashCells {
+		fmt.Println("geohashCells expected 1 to", maxGeohashCells, "cells, Actual:", cells)
+		t.FailNow()
+	}
+	for _, point := range [][2]float64{{49, 8}, {50, 9}, {49.5, 8.5}, {49, 9}} {
+		geohash := encodeGeohash(point[0], point[1], geohashPrecision)
+		covered := false
+		for _, cell := range cells {
+			covered = covered || geohash[:len(cell)] == cell
+		}
+		if !covered {
+			fmt.Println("geohashCells", cells, "do not cover", point)
+			t.FailNow()
+		}
+	}
+}
+
+//TestGeohash_geohashBounds
+func TestGeohash_geohashBounds(t *testing.T) {
+	minLatitude, minLongitude, maxLatitude, maxLongitude := geohashBounds("ezs42")
+	if minLatitude > 42.6 || maxLatitude < 42.6 || minLongitude > -5.6 || maxLongitude < -5.6 ||
+		maxLatitude-minLatitude != 180.0/(1<<12) || maxLongitude-minLongitude != 360.0/(1<<13) {
+		fmt.Println("geohashBounds of ezs42 expected the cell of 42.6, -5.6, Actual:", minLatitude, minLongitude, maxLatitude, maxLongitude)
+		t.FailNow()
+	}
+	minLatitude, minLongitude, maxLatitude, maxLongitude = geohashBounds("z")
+	if minLatitude != 45 || minLongitude != 135 || maxLatitude != 90 || maxLongitude != 180 {
+		fmt.Println("geohashBounds of z expected 45, 135, 90, 180, Actual:", minLatitude, minLongitude, maxLatitude, maxLongitude)
+		t.FailNow()
+	}
+}
+
+//TestGeohash_queryReadingsNear
+func TestGeohash_queryReadingsNear(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"latitude\":49.4093"))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Reading Data is Corrupted: latitude: is not a known field")
+	checkLocatedReading(t, stub, "1", 49.4093, 8.6942, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ""))
+	checkLocatedReading(t, stub, "1", 52.52, 13.405, getGeoReadingForTesting("addNewReading", "100002", 70, "2017-12-01T10:15:00+01:00", ""))
+	checkLocatedReading(t, stub, "tx2", 52.5201, 13.4049, getGeoReadingForTesting("updateReading", "100001", 100, "2017-12-20T08:30:00-05:00", ""))
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte("100001")})
+	var current Reading
+	err := json.Unmarshal(res.Payload, &current)
+	if err != nil || current.Geohash != encodeGeohash(52.5201, 13.4049, geohashPrecision) || strings.Contains(string(res.Payload), "52.52") {
+		fmt.Println("Reading expected only the coarse geohash of its location, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	checkQueryReadingsOK(t, stub, "{\"vehicleID\":\"100001\"}", []string{"100001"})
+	checkQueryReadingsNear(t, stub, "{\"geohash\":\""+encodeGeohash(49.4093, 8.6942, 4)+"\"}", []string{"100001/1"})
+	berlin := "\"minLatitude\":52.5,\"minLongitude\":13.4,\"maxLatitude\":52.53,\"maxLongitude\":13.41"
+	checkQueryReadingsNear(t, stub, "{"+berlin+"}", []string{"100001/tx2", "100002/1"})
+	checkQueryReadingsNear(t, stub, "{"+berlin+",\"from\":\"2017-12-10T00:00:00Z\",\"to\":\"2017-12-31T00:00:00Z\"}", []string{"100001/tx2"})
+	checkQueryReadingsNear(t, stub, "{\"minLatitude\":52,\"minLongitude\":13,\"maxLatitude\":52.1,\"maxLongitude\":13.1}", []string{})
+	res = stub.MockInvoke("1", [][]byte{[]byte("queryReadingsNear"), []byte("{" + berlin + "}"), []byte("1"), []byte("u0:")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest, "queryReadingsNear: Bookmark does not belong to this query")
+	tests := map[string]string{
+		"{\"geohash\":\"u0a\"}":                  "geohash: must be a geohash of 1 to 5 characters of " + geohashAlphabet,
+		"{\"geohash\":\"u0\",\"minLatitude\":1}": "$: expects either geohash or minLatitude, minLongitude, maxLatitude and maxLongitude",
+		"{\"minLatitude\":1,\"minLongitude\":170,\"maxLatitude\":2,\"maxLongitude\":-170}": "$: minimum bounds must not exceed maximum " +
+			"bounds - query boxes crossing the antimeridian as two boxes",
+		"{\"minLatitude\":-91,\"minLongitude\":1,\"maxLatitude\":1,\"maxLongitude\":2}": "minLatitude: must be between -90 and 90",
+		"{\"geohash\":\"u0\",\"from\":\"yesterday\"}":                                   "from: must be an RFC 3339 timestamp with time zone offset, e.g. 2017-12-01T10:15:00+01:00",
+	}
+	for input, expectedErr := range tests {
+		res = stub.MockInvoke("1", [][]byte{[]byte("queryReadingsNear"), []byte(input)})
+		checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed, "queryReadingsNear: Query is Corrupted: "+expectedErr)
+	}
+}
+
+//TestGeohash_removeAllReadings
+func TestGeohash_removeAllReadings(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInitWithPurgeConfirmation(t, stub)
+	checkRegisterVehicles(t, stub, "100001")
+	checkLocatedReading(t, stub, "1", 52.52, 13.405, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ""))
+	query := "{\"geohash\":\"" + encodeGeohash(52.52, 13.405, geohashPrecision) + "\"}"
+	checkQueryReadingsNear(t, stub, query, []string{"100001/1"})
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkRemoveAllReadings(t, stub)
+	checkQueryReadingsNear(t, stub, query, []string{})
+}
+
+//checkQueryReadingsNear - helper checking the vehicleID/txID of the readings returned by queryReadingsNear, both in one
+//page and paging through them one index entry at a time
+func checkQueryReadingsNear(t *testing.T, stub *ExtendedMockStub, query string, expected []string) {
+	page := queryReadingsNearForTesting(t, stub, query, "", "")
+	if page.Bookmark != "" {
+		fmt.Println("func queryReadingsNear", query, "expected a single page, Actual bookmark:", page.Bookmark)
+		t.FailNow()
+	}
+	checkReadingsNear(t, query, page.Records, expected)
+	readings := []Reading{}
+	bookmark := ""
+	for pages := 0; pages == 0 || bookmark != ""; pages++ {
+		page = queryReadingsNearForTesting(t, stub, query, "1", bookmark)
+		if page.FetchedCount > 1 || pages > 2*len(expected)+maxGeohashCells {
+			fmt.Println("func queryReadingsNear", query, "expected pages of one index entry, Actual:", page)
+			t.FailNow()
+		}
+		readings = append(readings, page.Records...)
+		bookmark = page.Bookmark
+	}
+	checkReadingsNear(t, query, readings, expected)
+}
+
+//checkReadingsNear - helper comparing the vehicleID/txID of the readings returned by queryReadingsNear
+func checkReadingsNear(t *testing.T, query string, readings []Reading, expected []string) {
+	actual := []string{}
+	for _, reading := range readings {
+		actual = append(actual, reading.VehicleID+"/"+reading.TxID)
+	}
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		fmt.Println("func queryReadingsNear", query, "Expected:", expected, "Actual:", actual)
+		t.FailNow()
+	}
+}
+
+//queryReadingsNearForTesting - helper returning one page of queryReadingsNear
+func queryReadingsNearForTesting(t *testing.T, stub *ExtendedMockStub, query string, pageSize string, bookmark string) ReadingPage {
+	var page ReadingPage
+	res := stub.MockInvoke("1", [][]byte{[]byte("queryReadingsNear"), []byte(query), []byte(pageSize), []byte(bookmark)})
+	err := json.Unmarshal(res.Payload, &page)
+	if res.Status != shim.OK || err != nil {
+		fmt.Println("func queryReadingsNear", query, "failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	return page
+}
+
+//checkLocatedReading - helper invoking a Reading with its private location in the transient field location
+func checkLocatedReading(t *testing.T, stub *ExtendedMockStub, txID string, latitude float64, longitude float64, args [][]byte) {
+	res := invokeWithTransientForTesting(stub, txID, transientLocation, getLocationForTesting(latitude, longitude), args)
+	if res.Status != shim.OK {
+		fmt.Println("func", string(args[0]), "with location failed", res.Message)
+		t.FailNow()
+	}
+}
+
+//Get a Reading with additional JSON fields for testing
+func getGeoReadingForTesting(function string, vehicleID string, value int, creationDate string, fields string) [][]byte {
+	return [][]byte{[]byte(function),
+		[]byte(fmt.Sprintf("{\"vehicleID\":\"%s\",\"docType\":\"Asset.Reading\",\"reading\":%d,\"unit\":\"km\",\"creationDate\":\"%s\"%s}",
+			vehicleID, value, creationDate, fields))}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//ExtendedMockStub - MockStub with the peer features the plain MockStub does not implement (creator identity, transient
+//data, key history, pagination, CouchDB rich queries, deletion of private data).
+//The chaincode is invoked with the ExtendedMockStub itself, so its overrides are visible to the routes.
+type ExtendedMockStub struct {
+	*shim.MockStub
+	Creator   []byte
+	Transient map[string][]byte
+	TxTime    time.Time
+	history   map[string][]*queryresult.KeyModification
+}
+
+//defaultTxTimeForTesting - deterministic transaction time, so expected values can contain the tx timestamp
+var defaultTxTimeForTesting = time.Date(2017, 12, 21, 9, 0, 0, 0, time.UTC)
+
+//chaincodeProxy - hands the ExtendedMockStub instead of the embedded MockStub to the chaincode
+type chaincodeProxy struct {
+	cc   shim.Chaincode
+	stub *ExtendedMockStub
+}
+
+func (proxy *chaincodeProxy) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	proxy.stub.startTransaction()
+	return proxy.cc.Init(proxy.stub)
+}
+
+func (proxy *chaincodeProxy) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	proxy.stub.startTransaction()
+	return proxy.cc.Invoke(proxy.stub)
+}
+
+//NewExtendedMockStub - constructs an ExtendedMockStub for the chaincode cc
+func NewExtendedMockStub(name string, cc shim.Chaincode) *ExtendedMockStub {
+	stub := &ExtendedMockStub{TxTime: defaultTxTimeForTesting, history: make(map[string][]*queryresult.KeyModification)}
+	stub.MockStub = shim.NewMockStub(name, &chaincodeProxy{cc: cc, stub: stub})
+	return stub
+}
+
+//startTransaction - replaces the wall clock timestamp set by MockTransactionStart with TxTime and discards the
+//events of earlier transactions, so ChaincodeEventsChannel only holds the events of the current one
+func (stub *ExtendedMockStub) startTransaction() {
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: stub.TxTime.Unix(), Nanos: int32(stub.TxTime.Nanosecond())}
+	for len(stub.ChaincodeEventsChannel) > 0 {
+		<-stub.ChaincodeEventsChannel
+	}
+}
+
+//GetCreator - returns the serialized identity set as Creator
+func (stub *ExtendedMockStub) GetCreator() ([]byte, error) {
+	return stub.Creator, nil
+}
+
+//GetTransient - returns the transient data set as Transient
+func (stub *ExtendedMockStub) GetTransient() (map[string][]byte, error) {
+	return stub.Transient, nil
+}
+
+//DelPrivateData - deletes the value from the collection
+func (stub *ExtendedMockStub) DelPrivateData(collection string, key string) error {
+	delete(stub.PvtState[collection], key)
+	return nil
+}
+
+//PutState - stores the value and records it in the key history
+func (stub *ExtendedMockStub) PutState(key string, value []byte) error {
+	err := stub.MockStub.PutState(key, value)
+	if err != nil {
+		return err
+	}
+	stub.recordHistory(key, value, false)
+	return nil
+}
+
+//DelState - deletes the value and records the deletion in the key history
+func (stub *ExtendedMockStub) DelState(key string) error {
+	err := stub.MockStub.DelState(key)
+	if err != nil {
+		return err
+	}
+	stub.recordHistory(key, nil, true)
+	return nil
+}
+
+//GetHistoryForKey - returns the recorded modifications of a key, newest first like the peer does
+func (stub *ExtendedMockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	modifications := stub.history[key]
+	newestFirst := make([]*queryresult.KeyModification, 0, len(modifications))
+	for i := len(modifications) - 1; i >= 0; i-- {
+		newestFirst = append(newestFirst, modifications[i])
+	}
+	return &mockHistoryQueryIterator{modifications: newestFirst}, nil
+}
+
+//GetStateByPartialCompositeKeyWithPagination - pages over the composite keys; the bookmark is the first key of the next page
+func (stub *ExtendedMockStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string,
+	pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, err := stub.MockStub.GetStateByPartialCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iterator.Close()
+	var page []*queryresult.KV
+	nextBookmark := ""
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if kv.Key < bookmark {
+			continue
+		}
+		if int32(len(page)) == pageSize {
+			nextBookmark = kv.Key
+			break
+		}
+		page = append(page, kv)
+	}
+	metadata := &peer.QueryResponseMetadata{FetchedRecordsCount: int32(len(page)), Bookmark: nextBookmark}
+	return &mockStateQueryIterator{kvs: page}, metadata, nil
+}
+
+//GetQueryResult - evaluates the Mango selector of query against every JSON document in state
+func (stub *ExtendedMockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := stub.GetQueryResultWithPagination(query, 0, "")
+	return iterator, err
+}
+
+//GetQueryResultWithPagination - evaluates the Mango selector of query like CouchDB; a page size of 0 returns all matches
+func (stub *ExtendedMockStub) GetQueryResultWithPagination(query string, pageSize int32,
+	bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	var parsedQuery struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	err := json.Unmarshal([]byte(query), &parsedQuery)
+	if err != nil || parsedQuery.Selector == nil {
+		return nil, nil, fmt.Errorf("invalid query %s", query)
+	}
+	var page []*queryresult.KV
+	nextBookmark := ""
+	for elem := stub.Keys.Front(); elem != nil; elem = elem.Next() {
+		key := elem.Value.(string)
+		if key < bookmark {
+			continue
+		}
+		var doc map[string]interface{}
+		if json.Unmarshal(stub.State[key], &doc) != nil {
+			continue
+		}
+		matches, err := matchSelector(doc, parsedQuery.Selector)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !matches {
+			continue
+		}
+		if pageSize > 0 && int32(len(page)) == pageSize {
+			nextBookmark = key
+			break
+		}
+		page = append(page, &queryresult.KV{Key: key, Value: stub.State[key]})
+	}
+	metadata := &peer.QueryResponseMetadata{FetchedRecordsCount: int32(len(page)), Bookmark: nextBookmark}
+	return &mockStateQueryIterator{kvs: page}, metadata, nil
+}
+
+func (stub *ExtendedMockStub) recordHistory(key string, value []byte, isDelete bool) {
+	stub.history[key] = append(stub.history[key], &queryresult.KeyModification{
+		TxId:      stub.TxID,
+		Value:     value,
+		Timestamp: stub.TxTimestamp,
+		IsDelete:  isDelete,
+	})
+}
+
+//mockHistoryQueryIterator - iterator over recorded key modifications
+type mockHistoryQueryIterator struct {
+	modifications []*queryresult.KeyModification
+	position      int
+}
+
+func (iter *mockHistoryQueryIterator) HasNext() bool {
+	return iter.position < len(iter.modifications)
+}
+
+func (iter *mockHistoryQueryIterator) Next() (*queryresult.KeyModification, error) {
+	if !iter.HasNext() {
+		return nil, errors.New("mockHistoryQueryIterator: Next() called when it does not HaveNext()")
+	}
+	modification := iter.modifications[iter.position]
+	iter.position++
+	return modification, nil
+}
+
+func (iter *mockHistoryQueryIterator) Close() error {
+	return nil
+}
+
+//mockStateQueryIterator - iterator over a fixed list of key/value pairs
+type mockStateQueryIterator struct {
+	kvs      []*queryresult.KV
+	position int
+}
+
+func (iter *mockStateQueryIterator) HasNext() bool {
+	return iter.position < len(iter.kvs)
+}
+
+func (iter *mockStateQueryIterator) Next() (*queryresult.KV, error) {
+	if !iter.HasNext() {
+		return nil, errors.New("mockStateQueryIterator: Next() called when it does not HaveNext()")
+	}
+	kv := iter.kvs[iter.position]
+	iter.position++
+	return kv, nil
+}
+
+func (iter *mockStateQueryIterator) Close() error {
+	return nil
+}
+
+//matchSelector - evaluates the subset of Mango selectors used by the chaincode: field paths, $and, $or, $not,
+//$eq, $ne, $gt, $gte, $lt, $lte, $in, $nin and $exists
+func matchSelector(doc map[string]interface{}, selector map[string]interface{}) (bool, error) {
+	for field, condition := range selector {
+		var matches bool
+		var err error
+		switch field {
+		case "$and", "$or":
+			subSelectors, ok := condition.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("%s expects an array", field)
+			}
+			matches = field == "$and"
+			for _, subSelector := range subSelectors {
+				subMap, ok := subSelector.(map[string]interface{})
+				if !ok {
+					return false, fmt.Errorf("%s expects an array of selectors", field)
+				}
+				subMatches, err := matchSelector(doc, subMap)
+				if err != nil {
+					return false, err
+				}
+				if field == "$and" {
+					matches = matches && subMatches
+				} else {
+					matches = matches || subMatches
+				}
+			}
+		case "$not":
+			subMap, ok := condition.(map[string]interface{})
+			if !ok {
+				return false, errors.New("$not expects a selector")
+			}
+			matches, err = matchSelector(doc, subMap)
+			matches = !matches
+		default:
+			value, exists := lookupField(doc, field)
+			matches, err = matchCondition(value, exists, condition)
+		}
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func lookupField(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func matchCondition(value interface{}, exists bool, condition interface{}) (bool, error) {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return exists && collate(value, condition) == 0, nil
+	}
+	for operator, operand := range operators {
+		var matches bool
+		switch operator {
+		case "$eq":
+			matches = exists && collate(value, operand) == 0
+		case "$ne":
+			matches = !exists || collate(value, operand) != 0
+		case "$gt":
+			matches = exists && sameType(value, operand) && collate(value, operand) > 0
+		case "$gte":
+			matches = exists && sameType(value, operand) && collate(value, operand) >= 0
+		case "$lt":
+			matches = exists && sameType(value, operand) && collate(value, operand) < 0
+		case "$lte":
+			matches = exists && sameType(value, operand) && collate(value, operand) <= 0
+		case "$in", "$nin":
+			candidates, ok := operand.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("%s expects an array", operator)
+			}
+			for _, candidate := range candidates {
+				if exists && collate(value, candidate) == 0 {
+					matches = true
+				}
+			}
+			if operator == "$nin" {
+				matches = exists && !matches
+			}
+		case "$exists":
+			matches = exists == (operand == true)
+		default:
+			return false, fmt.Errorf("operator %s not supported by the mock", operator)
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//sameType - CouchDB range operators only match values of the operand's JSON type
+func sameType(a interface{}, b interface{}) bool {
+	return collationRank(a) == collationRank(b)
+}
+
+//collationRank - CouchDB collation order: null < false < true < numbers < strings < arrays < objects
+func collationRank(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
+
+func collate(a interface{}, b interface{}) int {
+	rankA, rankB := collationRank(a), collationRank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+	switch v := a.(type) {
+	case float64:
+		w := b.(float64)
+		if v < w {
+			return -1
+		} else if v > w {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(v, b.(string))
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return 1
+}
+
+//attributesOID - certificate extension in which the Fabric CA stores attributes
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+//getCreatorForTesting - serialized identity of MSP mspID with a self-signed certificate carrying the role attribute
+func getCreatorForTesting(mspID string, role string) []byte {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	attributes, err := json.Marshal(map[string]map[string]string{"attrs": {"role": role}})
+	if err != nil {
+		panic(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: role + "@" + mspID, Organization: []string{mspID}},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: attributesOID, Value: attributes}},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		panic(err)
+	}
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: certificatePEM})
+	if err != nil {
+		panic(err)
+	}
+	return creator
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//Units of odometer values
+const (
+	unitKilometres = "km"
+	unitMiles      = "mi"
+)
+
+//kmPerMile - international mile in kilometres
+const kmPerMile = 1.609344
+
+//unitConversionTolerance - odometers display whole units, so a value read in km right after one read in mi may be
+//up to one mile lower than the converted value without rolling back (in km)
+const unitConversionTolerance = kmPerMile
+
+//OdometerValue - numeric odometer value. Legacy records stored it as a JSON string, which is still accepted
+//if it holds a valid number.
+type OdometerValue float64
+
+//UnmarshalJSON - accepts a JSON number or a string holding a number; NaN and infinities are rejected
+func (value *OdometerValue) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), "\"")
+	if text == "null" {
+		return errors.New("Reading value must not be null")
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return errors.New("Reading value " + string(data) + " is not a valid number")
+	}
+	*value = OdometerValue(number)
+	return nil
+}
+
+//normalizeUnit - legacy records without unit are in km
+func normalizeUnit(unit string) string {
+	if unit == "" {
+		return unitKilometres
+	}
+	return unit
+}
+
+//toKilometres - converts an odometer value of the given unit
+func toKilometres(value OdometerValue, unit string) float64 {
+	if normalizeUnit(unit) == unitMiles {
+		return float64(value) * kmPerMile
+	}
+	return float64(value)
+}
+
+//checkReadingValue - odometer values must not be negative
+func checkReadingValue(value float64) string {
+	if value < 0 {
+		return "must not be negative"
+	}
+	return ""
+}
+
+//trueKilometres - the mileage of a reading in km including the offset carried over from replaced odometers
+func trueKilometres(reading Reading) float64 {
+	return toKilometres(reading.Reading, reading.Unit) + float64(reading.OdometerOffset)
+}
+
+//isMileageRollback - compares the true mileage of two readings in km; if the units differ the rounding of the
+//display is tolerated
+func isMileageRollback(currReading Reading, newReading Reading) bool {
+	currKm := trueKilometres(currReading)
+	newKm := trueKilometres(newReading)
+	if normalizeUnit(currReading.Unit) != normalizeUnit(newReading.Unit) {
+		return newKm+unitConversionTolerance < currKm
+	}
+	return newKm < currKm
+}
+
+//setTrueMileage - after an odometer replacement the true mileage is the reading in km plus the carried-over offset
+func setTrueMileage(reading *Reading) {
+	reading.TrueMileage = 0
+	if reading.OdometerOffset != 0 {
+		reading.TrueMileage = OdometerValue(trueKilometres(*reading))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestOdometer_UnmarshalJSON
+func TestOdometer_UnmarshalJSON(t *testing.T) {
+	var reading Reading
+	for input, expected := range map[string]OdometerValue{"50": 50, "\"50\"": 50, "123456.7": 123456.7} {
+		err := json.Unmarshal([]byte("{\"reading\":"+input+"}"), &reading)
+		if err != nil || reading.Reading != expected {
+			fmt.Println("Unmarshal of reading", input, "Expected:", expected, "Actual:", reading.Reading, err)
+			t.FailNow()
+		}
+	}
+	for _, input := range []string{"\"abc\"", "\"NaN\"", "\"-Inf\"", "null", "true", "\"\""} {
+		err := json.Unmarshal([]byte("{\"reading\":"+input+"}"), &reading)
+		if err == nil {
+			fmt.Println("Unmarshal of malformed reading", input, "was expected to fail")
+			t.FailNow()
+		}
+	}
+}
+
+//TestOdometer_isMileageRollback
+func TestOdometer_isMileageRollback(t *testing.T) {
+	tests := []struct {
+		curr     Reading
+		new      Reading
+		rollback bool
+	}{
+		{Reading{Reading: 100, Unit: "km"}, Reading{Reading: 100, Unit: "km"}, false},
+		{Reading{Reading: 100, Unit: "km"}, Reading{Reading: 99.9, Unit: "km"}, true},
+		{Reading{Reading: 100, Unit: "mi"}, Reading{Reading: 161, Unit: "km"}, false},
+		{Reading{Reading: 100, Unit: "mi"}, Reading{Reading: 160, Unit: "km"}, false},
+		{Reading{Reading: 100, Unit: "mi"}, Reading{Reading: 150, Unit: "km"}, true},
+		{Reading{Reading: 161, Unit: "km"}, Reading{Reading: 100, Unit: "mi"}, false},
+		{Reading{Reading: 200, Unit: "km"}, Reading{Reading: 100, Unit: "mi"}, true},
+		{Reading{Reading: 100, Unit: ""}, Reading{Reading: 100, Unit: "km"}, false},
+		{Reading{Reading: 100, Unit: ""}, Reading{Reading: 99, Unit: "km"}, true},
+		{Reading{Reading: 100000, Unit: "km"}, Reading{Reading: 10, Unit: "km", OdometerOffset: 99995}, false},
+		{Reading{Reading: 10, Unit: "km", OdometerOffset: 99995}, Reading{Reading: 9, Unit: "km", OdometerOffset: 99995}, true},
+	}
+	for _, test := range tests {
+		if isMileageRollback(test.curr, test.new) != test.rollback {
+			fmt.Println("isMileageRollback from", test.curr, "to", test.new, "Expected:", test.rollback)
+			t.FailNow()
+		}
+	}
+}
+
+//TestOdometer_malformedReadings
+func TestOdometer_malformedReadings(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	tests := map[string]string{
+		"\"reading\":\"abc\",\"unit\":\"km\"": "reading: must be a number",
+		"\"reading\":\"50\",\"unit\":\"km\"":  "reading: must be a number",
+		"\"reading\":-5,\"unit\":\"km\"":      "reading: must not be negative",
+		"\"reading\":50,\"unit\":\"m\"":       "unit: must be one of: km, mi",
+	}
+	for input, expectedErr := range tests {
+		res := stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", input, "2017-12-01T10:15:00+01:00"))
+		if res.Status == shim.OK {
+			fmt.Println("addNewReading with", input, "was expected to fail")
+			t.FailNow()
+		}
+		checkError(t, "Reading Data is Corrupted: "+expectedErr, res.Message)
+	}
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":\"abc\",\"unit\":\"km\"", "2017-12-20T08:30:00-05:00"))
+	if res.Status == shim.OK {
+		fmt.Println("updateReading with malformed reading was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "updateReading: Reading Data is Corrupted: reading: must be a number", res.Message)
+	checkState(t, stub, "100001", getNewReadingExpected())
+}
+
+//TestOdometer_updateReadingUnits
+func TestOdometer_updateReadingUnits(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getReadingForOdometerTesting("addNewReading", "\"reading\":100,\"unit\":\"mi\"", "2017-12-01T10:15:00+01:00"))
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":150,\"unit\":\"km\"", "2017-12-10T10:00:00Z"))
+	if res.Status == shim.OK {
+		fmt.Println("updateReading from 100 mi to 150 km was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "updateReading: New Reading is less than Current Reading - cannot update", res.Message)
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":170,\"unit\":\"km\"", "2017-12-10T10:00:00Z"))
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":104,\"unit\":\"mi\"", "2017-12-20T08:30:00-05:00"))
+	if res.Status == shim.OK {
+		fmt.Println("updateReading from 170 km to 104 mi was expected to fail")
+		t.FailNow()
+	}
+}
+
+//TestOdometer_legacyReading
+func TestOdometer_legacyReading(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	stub.MockTransactionStart("0")
+	stub.PutState("100001", []byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":\"50\",\"creationDate\":\"12/01/2017\"}"))
+	stub.MockTransactionEnd("0")
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte("100001")})
+	var actual Reading
+	err := json.Unmarshal(res.Payload, &actual)
+	if res.Status != shim.OK || err != nil || actual.Reading != 50 || actual.Unit != "km" {
+		fmt.Println("func readReading of legacy reading failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+}
+
+//Get a Reading for vehicle 100001 with the given value and unit JSON fields for testing
+func getReadingForOdometerTesting(function string, valueAndUnit string, creationDate string) [][]byte {
+	return [][]byte{[]byte(function),
+		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\"," + valueAndUnit + ",\"creationDate\":\"" + creationDate + "\"}")}
+}
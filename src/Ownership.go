@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//transferKeyType - object type of the composite keys transfer~vehicle~seq of the ownership transfers
+const transferKeyType = "transfer~vehicle~seq"
+
+//OwnershipTransfer - Change of the owner of a vehicle with the mileage at that moment, taken from the latest Reading,
+//stored under the composite key transfer~vehicle~seq. Mileage is the true mileage in km.
+type OwnershipTransfer struct {
+	ObjectType       string        `json:"docType"`
+	VehicleID        string        `json:"vehicleID"`
+	Sequence         int           `json:"seq"`
+	SellerID         string        `json:"sellerID"`
+	BuyerID          string        `json:"buyerID"`
+	TransferDate     string        `json:"transferDate"`
+	Reading          OdometerValue `json:"reading"`
+	Unit             string        `json:"unit"`
+	Mileage          OdometerValue `json:"mileage"`
+	ReadingDate      string        `json:"readingDate"`
+	ReadingTxID      string        `json:"readingTxID"`
+	SubmitterMSPID   string        `json:"submitterMSPID"`
+	SubmitterSubject string        `json:"submitterSubject"`
+	TxID             string        `json:"txID"`
+	TxTimestamp      string        `json:"txTimestamp"`
+}
+
+//OwnershipPeriod - One owner of a vehicle with the mileage (km) at the start and end of the ownership. The mileage at
+//the start is unknown for the first recorded owner; the current owner's period ends with the latest Reading.
+type OwnershipPeriod struct {
+	OwnerID      string         `json:"ownerID"`
+	From         string         `json:"from,omitempty"`
+	To           string         `json:"to,omitempty"`
+	StartMileage *OdometerValue `json:"startMileage,omitempty"`
+	EndMileage   *OdometerValue `json:"endMileage,omitempty"`
+	Driven       *OdometerValue `json:"driven,omitempty"`
+	Current      bool           `json:"current"`
+}
+
+//OwnershipChain - Ownership transfers of a vehicle, oldest first, and the owners between them
+type OwnershipChain struct {
+	VehicleID string              `json:"vehicleID"`
+	Transfers []OwnershipTransfer `json:"transfers"`
+	Owners    []OwnershipPeriod   `json:"owners"`
+}
+
+//transferSchema - schema of the OwnershipTransfer JSON accepted by transferOwnership
+var transferSchema = []fieldSchema{
+	{name: "vehicleID", required: true, validate: stringField(checkID)},
+	{name: "sellerID", required: true, validate: stringField(checkID)},
+	{name: "buyerID", required: true, validate: stringField(checkID)},
+	{name: "transferDate", required: true, validate: stringField(checkTimestamp)},
+}
+
+//Invoke Route: transferOwnership - the seller must be the current owner of the vehicle, if one is recorded. The
+//buyer's personal data in the transient field ownerDetails replaces the seller's, which is deleted otherwise.
+func (rdg *ReadingAsset) transferOwnership(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	var currReading Reading
+	err := rdg.checkAccess(stub, vehicleRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("transferOwnership: ", err))
+	}
+	transfer, err := getTransferFromArgs(args)
+	if err != nil {
+		return validationFailed("transferOwnership: Transfer Data is Corrupted", err)
+	}
+	ownerDetails, err := getOwnerDetailsFromTransient(stub, Vehicle{VehicleID: transfer.VehicleID, OwnerID: transfer.BuyerID})
+	if err != nil {
+		return validationFailed("transferOwnership: Owner Details are Corrupted", err)
+	}
+	err = rdg.checkReadingTime(stub, transfer.TransferDate)
+	if err != nil {
+		return errorResponse(withPrefix("transferOwnership: ", err))
+	}
+	vehicle, err := rdg.retrieveVehicle(stub, transfer.VehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("transferOwnership: ", err))
+	}
+	if vehicle.OwnerID != "" && vehicle.OwnerID != transfer.SellerID {
+		return conflict(codeOwnerMismatch, "transferOwnership: Seller "+transfer.SellerID+" is not the current owner of vehicle with ID: "+vehicle.VehicleID)
+	}
+	seq, err := lastSequence(stub, transferKeyType, transfer.VehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if seq > 0 {
+		lastTransfer, err := rdg.retrieveOwnershipTransfer(stub, transfer.VehicleID, seq)
+		if err != nil {
+			return errorResponse(err)
+		}
+		lastDate, err := parseReadingTime(lastTransfer.TransferDate)
+		if err != nil {
+			return errorResponse(withPrefix("transferOwnership: ", err))
+		}
+		transferDate, err := parseReadingTime(transfer.TransferDate)
+		if err != nil {
+			return errorResponse(withPrefix("transferOwnership: ", err))
+		}
+		if lastDate.After(transferDate) {
+			return conflict(codeDateRegression, "transferOwnership: Transfer Date is earlier than the last Transfer Date")
+		}
+	}
+	readingAsByteArray, err := rdg.retrieveReading(stub, transfer.VehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("transferOwnership: ", err))
+	}
+	err = json.Unmarshal(readingAsByteArray, &currReading)
+	if err != nil {
+		return internalError("transferOwnership: Error unmarshalling reading JSON")
+	}
+	transfer.Reading = currReading.Reading
+	transfer.Unit = currReading.Unit
+	transfer.Mileage = OdometerValue(trueKilometres(currReading))
+	transfer.ReadingDate = currReading.CreationDate
+	transfer.ReadingTxID = currReading.TxID
+	transfer.ObjectType = "Asset.OwnershipTransfer"
+	transfer.Sequence, err = nextSequence(stub, transferKeyType, transfer.VehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	vehicle.OwnerID = transfer.BuyerID
+	err = stampVehicle(stub, &vehicle)
+	if err != nil {
+		return errorResponse(withPrefix("transferOwnership: ", err))
+	}
+	transfer.SubmitterMSPID = vehicle.SubmitterMSPID
+	transfer.SubmitterSubject = vehicle.SubmitterSubject
+	transfer.TxID = vehicle.TxID
+	transfer.TxTimestamp = vehicle.TxTimestamp
+	if ownerDetails != nil {
+		vehicle.OwnerDetailsHash, err = privateDataHash(*ownerDetails)
+		if err != nil {
+			return errorResponse(withPrefix("transferOwnership: ", err))
+		}
+		_, err = rdg.saveOwnerDetails(stub, *ownerDetails)
+	} else if vehicle.OwnerDetailsHash != "" {
+		vehicle.OwnerDetailsHash = ""
+		_, err = rdg.deleteOwnerDetails(stub, vehicle.VehicleID)
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+	_, err = rdg.saveVehicle(stub, vehicle)
+	if err != nil {
+		return errorResponse(err)
+	}
+	bytes, err := rdg.saveOwnershipTransfer(stub, transfer)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = stub.SetEvent(eventOwnershipTransferred, bytes)
+	if err != nil {
+		return internalError("transferOwnership: Error setting OwnershipTransferred event")
+	}
+	return shim.Success(nil)
+}
+
+//Query Route: readOwnershipChain - the owners of a vehicle with the mileage each of them drove
+func (rdg *ReadingAsset) readOwnershipChain(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	var currReading Reading
+	vehicle, err := rdg.retrieveVehicle(stub, vehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("readOwnershipChain: ", err))
+	}
+	transfers, err := rdg.retrieveOwnershipTransfers(stub, vehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if len(transfers) == 0 && vehicle.OwnerID == "" {
+		return notFound("readOwnershipChain: No owners recorded for vehicle with ID: " + vehicleID)
+	}
+	chain := OwnershipChain{VehicleID: vehicleID, Transfers: transfers, Owners: []OwnershipPeriod{}}
+	var startMileage *OdometerValue
+	from := ""
+	for i := range transfers {
+		chain.Owners = append(chain.Owners, newOwnershipPeriod(transfers[i].SellerID, from, transfers[i].TransferDate,
+			startMileage, &transfers[i].Mileage))
+		startMileage = &transfers[i].Mileage
+		from = transfers[i].TransferDate
+	}
+	var endMileage *OdometerValue
+	readingAsByteArray, err := rdg.retrieveReading(stub, vehicleID)
+	if err == nil && json.Unmarshal(readingAsByteArray, &currReading) == nil {
+		mileage := OdometerValue(trueKilometres(currReading))
+		endMileage = &mileage
+	}
+	current := newOwnershipPeriod(vehicle.OwnerID, from, "", startMileage, endMileage)
+	current.Current = true
+	chain.Owners = append(chain.Owners, current)
+	bytes, err := json.Marshal(chain)
+	if err != nil {
+		return internalError("readOwnershipChain: Error marshalling ownership chain JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//newOwnershipPeriod - period of an owner, with the mileage driven if both the start and end mileage are known
+func newOwnershipPeriod(ownerID string, from string, to string, startMileage *OdometerValue, endMileage *OdometerValue) OwnershipPeriod {
+	period := OwnershipPeriod{OwnerID: ownerID, From: from, To: to, StartMileage: startMileage, EndMileage: endMileage}
+	if startMileage != nil && endMileage != nil {
+		driven := *endMileage - *startMileage
+		period.Driven = &driven
+	}
+	return period
+}
+
+//Helper: Save ownership transfer - returns the stored JSON, which is also the payload of the OwnershipTransferred event
+func (rdg *ReadingAsset) saveOwnershipTransfer(stub shim.ChaincodeStubInterface, transfer OwnershipTransfer) ([]byte, error) {
+	transferKey, err := createSequenceKey(stub, transferKeyType, transfer.VehicleID, transfer.Sequence)
+	if err != nil {
+		return nil, errors.New("saveOwnershipTransfer: Error creating transfer key for vehicle with ID: " + transfer.VehicleID)
+	}
+	bytes, err := json.Marshal(transfer)
+	if err != nil {
+		return nil, errors.New("saveOwnershipTransfer: Error converting ownership transfer record JSON")
+	}
+	err = stub.PutState(transferKey, bytes)
+	if err != nil {
+		return nil, errors.New("saveOwnershipTransfer: Error storing ownership transfer record")
+	}
+	return bytes, nil
+}
+
+//Helper: Retrieve ownership transfer - the transfer with sequence number seq of a vehicle
+func (rdg *ReadingAsset) retrieveOwnershipTransfer(stub shim.ChaincodeStubInterface, vehicleID string, seq int) (OwnershipTransfer, error) {
+	var transfer OwnershipTransfer
+	bytes, err := retrieveRecordValue(stub, transferKeyType, vehicleID, seq)
+	if err != nil {
+		return transfer, err
+	}
+	err = json.Unmarshal(bytes, &transfer)
+	if err != nil {
+		return transfer, errors.New("retrieveOwnershipTransfer: Corrupt ownership transfer record " + string(bytes))
+	}
+	return transfer, nil
+}
+
+//Helper: Retrieve ownership transfers - the ownership transfers of a vehicle, oldest first
+func (rdg *ReadingAsset) retrieveOwnershipTransfers(stub shim.ChaincodeStubInterface, vehicleID string) ([]OwnershipTransfer, error) {
+	transfers := []OwnershipTransfer{}
+	values, err := retrieveRecordValues(stub, transferKeyType, vehicleID)
+	if err != nil {
+		return transfers, err
+	}
+	for _, value := range values {
+		var transfer OwnershipTransfer
+		err = json.Unmarshal(value, &transfer)
+		if err != nil {
+			return transfers, errors.New("retrieveOwnershipTransfers: Corrupt ownership transfer record " + string(value))
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+//getTransferFromArgs - construct an ownership transfer structure from string array of arguments
+func getTransferFromArgs(args []string) (transfer OwnershipTransfer, err error) {
+	if len(args) != 1 {
+		return transfer, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one OwnershipTransfer JSON argument"}}}
+	}
+	err = validateInput(args[0], transferSchema)
+	if err != nil {
+		return transfer, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &transfer)
+	if err != nil {
+		return transfer, err
+	}
+	if transfer.BuyerID == transfer.SellerID {
+		return transfer, ValidationError{Fields: []FieldError{{Field: "buyerID", Message: "must differ from sellerID"}}}
+	}
+	transfer.TransferDate, err = normalizeReadingTime(transfer.TransferDate)
+	if err != nil {
+		return transfer, err
+	}
+	return transfer, nil
+}
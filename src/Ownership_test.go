@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+//TestOwnership_transferOwnership
+func TestOwnership_transferOwnership(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkUnauthorized(t, stub, getTransferForTesting("owner-1", "owner-2", "2017-12-10T10:00:00Z"),
+		"transferOwnership: Role workshop is not authorized - requires one of: registry")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res := stub.MockInvoke("1", getTransferForTesting("owner-1", "owner-1", "2017-12-10T10:00:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"transferOwnership: Transfer Data is Corrupted: buyerID: must differ from sellerID")
+	checkInvoke(t, stub, getTransferForTesting("owner-1", "owner-2", "2017-12-10T10:00:00Z"))
+	event := <-stub.ChaincodeEventsChannel
+	var transfer OwnershipTransfer
+	err := json.Unmarshal(event.Payload, &transfer)
+	if event.EventName != eventOwnershipTransferred || err != nil || transfer.Sequence != 1 || transfer.Mileage != 50 ||
+		transfer.ReadingDate != "2017-12-01T09:15:00.000Z" || transfer.TransferDate != "2017-12-10T10:00:00.000Z" {
+		fmt.Println("Expected OwnershipTransferred event, Actual:", event.EventName, string(event.Payload))
+		t.FailNow()
+	}
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res = stub.MockInvoke("1", getTransferForTesting("owner-1", "owner-3", "2017-12-21T08:00:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeOwnerMismatch,
+		"transferOwnership: Seller owner-1 is not the current owner of vehicle with ID: 100001")
+	res = stub.MockInvoke("1", getTransferForTesting("owner-2", "owner-3", "2017-12-05T08:00:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeDateRegression,
+		"transferOwnership: Transfer Date is earlier than the last Transfer Date")
+	checkInvoke(t, stub, getTransferForTesting("owner-2", "owner-3", "2017-12-21T08:00:00Z"))
+	res = stub.MockInvoke("1", getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100001"), "US"))
+	if res.Status != 200 {
+		fmt.Println("updateVehicle without ownerID failed", res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("readVehicle"), []byte("100001")})
+	var vehicle Vehicle
+	err = json.Unmarshal(res.Payload, &vehicle)
+	if err != nil || vehicle.OwnerID != "owner-3" {
+		fmt.Println("Owner of vehicle expected: owner-3, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	input := getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100001"), "US")
+	input[1] = bytes.Replace(input[1], []byte("}"), []byte(",\"ownerID\":\"owner-9\"}"), 1)
+	res = stub.MockInvoke("1", input)
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"updateVehicle: Vehicle Data is Corrupted: ownerID: can only be changed by transferOwnership")
+}
+
+//TestOwnership_readOwnershipChain
+func TestOwnership_readOwnershipChain(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", [][]byte{[]byte("readOwnershipChain"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "readOwnershipChain: No owners recorded for vehicle with ID: 100001")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getTransferForTesting("owner-1", "owner-2", "2017-12-10T10:00:00Z"))
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getTransferForTesting("owner-2", "owner-3", "2017-12-21T08:00:00Z"))
+	res = stub.MockInvoke("1", [][]byte{[]byte("readOwnershipChain"), []byte("100001")})
+	var chain OwnershipChain
+	err := json.Unmarshal(res.Payload, &chain)
+	if err != nil || len(chain.Transfers) != 2 || len(chain.Owners) != 3 {
+		fmt.Println("func readOwnershipChain failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkOwnershipPeriod(t, chain.Owners[0], "owner-1", nil, 50, nil, false)
+	checkOwnershipPeriod(t, chain.Owners[1], "owner-2", newOdometerValue(50), 100, newOdometerValue(50), false)
+	checkOwnershipPeriod(t, chain.Owners[2], "owner-3", newOdometerValue(100), 100, newOdometerValue(0), true)
+}
+
+//checkOwnershipPeriod - helper for checking owner, mileage and driven distance of an ownership period
+func checkOwnershipPeriod(t *testing.T, period OwnershipPeriod, ownerID string, startMileage *OdometerValue, endMileage OdometerValue,
+	driven *OdometerValue, current bool) {
+	if period.OwnerID != ownerID || period.Current != current || period.EndMileage == nil || *period.EndMileage != endMileage ||
+		(startMileage == nil) != (period.StartMileage == nil) || (startMileage != nil && *startMileage != *period.StartMileage) ||
+		(driven == nil) != (period.Driven == nil) || (driven != nil && *driven != *period.Driven) {
+		periodJSON, _ := json.Marshal(period)
+		fmt.Println("Unexpected ownership period of", ownerID, "Actual:", string(periodJSON))
+		t.FailNow()
+	}
+}
+
+//Get an OdometerValue pointer for testing
+func newOdometerValue(value OdometerValue) *OdometerValue {
+	return &value
+}
+
+//Get an ownership transfer of vehicle 100001 for testing
+func getTransferForTesting(sellerID string, buyerID string, transferDate string) [][]byte {
+	return [][]byte{[]byte("transferOwnership"),
+		[]byte("{\"vehicleID\":\"100001\",\"sellerID\":\"" + sellerID + "\",\"buyerID\":\"" + buyerID + "\",\"transferDate\":\"" + transferDate + "\"}")}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//defaultCategory - plausibility rules applied to vehicles without a category or without rules for their category
+const defaultCategory = "default"
+
+//Actions of a plausibility rule for a reading above the maximum growth rate
+const (
+	actionReject = "reject"
+	actionFlag   = "flag"
+)
+
+//Flags of accepted readings failing a plausibility rule
+const (
+	flagHighGrowthRate   = "HIGH_GROWTH_RATE"
+	flagLowUsageThenJump = "LOW_USAGE_THEN_JUMP"
+)
+
+//minRateInterval - growth rates are computed over at least one hour, so readings taken minutes apart don't explode
+const minRateInterval = time.Hour
+
+//PlausibilityRule - Thresholds of the plausibility engine for one vehicle category, in km per day. Readings growing
+//faster than maxKmPerDay are rejected or flagged (onExceeded); a growth faster than jumpKmPerDay is flagged if the
+//previous interval of at least lowUsageMinDays grew slower than lowUsageKmPerDay. Zero thresholds are not checked.
+type PlausibilityRule struct {
+	MaxKmPerDay      float64 `json:"maxKmPerDay"`
+	OnExceeded       string  `json:"onExceeded,omitempty"`
+	LowUsageKmPerDay float64 `json:"lowUsageKmPerDay,omitempty"`
+	LowUsageMinDays  float64 `json:"lowUsageMinDays,omitempty"`
+	JumpKmPerDay     float64 `json:"jumpKmPerDay,omitempty"`
+}
+
+//checkPlausibilityRules - thresholds must not be negative and onExceeded must be reject (default) or flag
+func (config Config) checkPlausibilityRules() error {
+	for category, rule := range config.Plausibility {
+		if rule.MaxKmPerDay < 0 || rule.LowUsageKmPerDay < 0 || rule.LowUsageMinDays < 0 || rule.JumpKmPerDay < 0 {
+			return errors.New("plausibility thresholds of category " + category + " must not be negative")
+		}
+		if rule.OnExceeded != "" && rule.OnExceeded != actionReject && rule.OnExceeded != actionFlag {
+			return errors.New("onExceeded of category " + category + " must be one of: " + actionReject + ", " + actionFlag)
+		}
+	}
+	return nil
+}
+
+//plausibilityRule - the rule of the category, else the default rule; none if neither is configured
+func (config Config) plausibilityRule(category string) (PlausibilityRule, bool) {
+	rule, found := config.Plausibility[category]
+	if !found {
+		rule, found = config.Plausibility[defaultCategory]
+	}
+	return rule, found
+}
+
+//Helper: Check the growth of the mileage from currReading to newReading against the plausibility rule of the vehicle's
+//category - returns the flags of an accepted reading, or IMPLAUSIBLE_READING if the rule rejects it
+func (rdg *ReadingAsset) checkPlausibility(stub shim.ChaincodeStubInterface, currReading Reading, newReading Reading) ([]string, error) {
+	var flags []string
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return flags, err
+	}
+	category := ""
+	vehicle, err := rdg.retrieveVehicle(stub, newReading.VehicleID)
+	if err == nil {
+		category = vehicle.Category
+	}
+	rule, found := config.plausibilityRule(category)
+	if !found {
+		return flags, nil
+	}
+	rate, _, err := mileageRate(currReading, newReading)
+	if err != nil {
+		return flags, err
+	}
+	if rule.MaxKmPerDay > 0 && rate > rule.MaxKmPerDay {
+		if rule.OnExceeded != actionFlag {
+			return flags, newError(CONFLICT, codeImplausibleReading, fmt.Sprintf("Mileage growth of %.0f km/day exceeds the maximum of %.0f km/day",
+				rate, rule.MaxKmPerDay))
+		}
+		flags = append(flags, flagHighGrowthRate)
+	}
+	if rule.JumpKmPerDay > 0 && rate > rule.JumpKmPerDay {
+		seq, err := lastSequence(stub, readingHistoryKeyType, newReading.VehicleID)
+		if err != nil {
+			return flags, err
+		}
+		if seq >= 2 {
+			previousReading, err := rdg.retrieveReadingHistoryEntry(stub, newReading.VehicleID, seq-1)
+			if err != nil {
+				return flags, err
+			}
+			previousRate, previousDays, err := mileageRate(previousReading, currReading)
+			if err == nil && previousDays >= rule.LowUsageMinDays && previousRate < rule.LowUsageKmPerDay {
+				flags = append(flags, flagLowUsageThenJump)
+			}
+		}
+	}
+	return flags, nil
+}
+
+//mileageRate - growth of the true mileage from one reading to the next in km per day, and the days between them
+func mileageRate(fromReading Reading, toReading Reading) (rate float64, days float64, err error) {
+	fromDate, err := parseReadingTime(fromReading.CreationDate)
+	if err != nil {
+		return 0, 0, err
+	}
+	toDate, err := parseReadingTime(toReading.CreationDate)
+	if err != nil {
+		return 0, 0, err
+	}
+	interval := toDate.Sub(fromDate)
+	days = interval.Hours() / 24
+	interval = time.Duration(math.Max(float64(interval), float64(minRateInterval)))
+	return (trueKilometres(toReading) - trueKilometres(fromReading)) / (interval.Hours() / 24), days, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+//TestPlausibility_maxKmPerDay
+func TestPlausibility_maxKmPerDay(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"plausibility\":{\"default\":{\"maxKmPerDay\":2000},"+
+		"\"truck\":{\"maxKmPerDay\":2000,\"onExceeded\":\"flag\"}}"))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":900000,\"unit\":\"km\"", "2017-12-02T09:15:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeImplausibleReading,
+		"updateReading: Mileage growth of 899950 km/day exceeds the maximum of 2000 km/day")
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":1500,\"unit\":\"km\"", "2017-12-02T09:15:00Z"))
+	checkReadingFlags(t, stub, "100001", nil)
+
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	input := getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100001"), "US")
+	input[1] = bytes.Replace(input[1], []byte("}"), []byte(",\"category\":\"truck\"}"), 1)
+	checkInvoke(t, stub, input)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":900000,\"unit\":\"km\"", "2017-12-03T09:15:00Z"))
+	checkReadingEvent(t, stub, eventReadingUpdated,
+		ReadingEvent{VehicleID: "100001", OldValue: 1500, OldUnit: "km", NewValue: 900000, NewUnit: "km", Reason: flagHighGrowthRate})
+	checkReadingFlags(t, stub, "100001", []string{flagHighGrowthRate})
+}
+
+//TestPlausibility_lowUsageThenJump
+func TestPlausibility_lowUsageThenJump(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"plausibility\":{\"default\":{\"maxKmPerDay\":2000,"+
+		"\"lowUsageKmPerDay\":5,\"lowUsageMinDays\":30,\"jumpKmPerDay\":500}}"))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-10-01T10:00:00Z"))
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":60,\"unit\":\"km\"", "2017-12-01T10:00:00Z"))
+	checkReadingFlags(t, stub, "100001", nil)
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":1000,\"unit\":\"km\"", "2017-12-02T10:00:00Z"))
+	checkReadingFlags(t, stub, "100001", []string{flagLowUsageThenJump})
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":2000,\"unit\":\"km\"", "2017-12-03T10:00:00Z"))
+	checkReadingFlags(t, stub, "100001", nil)
+}
+
+//TestPlausibility_mileageRate
+func TestPlausibility_mileageRate(t *testing.T) {
+	from := Reading{Reading: 100, Unit: "km", CreationDate: "2017-12-01T10:00:00.000Z"}
+	tests := []struct {
+		to   Reading
+		rate float64
+		days float64
+	}{
+		{Reading{Reading: 200, Unit: "km", CreationDate: "2017-12-03T10:00:00.000Z"}, 50, 2},
+		{Reading{Reading: 110, Unit: "km", CreationDate: "2017-12-01T10:06:00.000Z"}, 240, 0.1 / 24},
+		{Reading{Reading: 10, Unit: "km", CreationDate: "2017-12-02T10:00:00.000Z", OdometerOffset: 190}, 100, 1},
+	}
+	for _, test := range tests {
+		rate, days, err := mileageRate(from, test.to)
+		if err != nil || rate != test.rate || days != test.days {
+			fmt.Println("mileageRate to", test.to, "Expected:", test.rate, test.days, "Actual:", rate, days, err)
+			t.FailNow()
+		}
+	}
+}
+
+//checkReadingFlags - helper for checking the plausibility flags of the current reading of a vehicle
+func checkReadingFlags(t *testing.T, stub *ExtendedMockStub, vehicleID string, expectedFlags []string) {
+	var reading Reading
+	err := json.Unmarshal(stub.State[vehicleID], &reading)
+	if err != nil || fmt.Sprint(reading.Flags) != fmt.Sprint(expectedFlags) {
+		fmt.Println("Flags of reading", vehicleID, "Expected:", expectedFlags, "Actual:", reading.Flags, err)
+		t.FailNow()
+	}
+}
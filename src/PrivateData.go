@@ -0,0 +1,324 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//Private data collections of collections_config.json, deployed with the chaincode - only the peers of their member
+//orgs store the records, the public ledger holds their hashes. The member orgs of the collection policies are the
+//sample orgs Org1MSP and Org2MSP: change them together with privateDataMSPIDs of the configuration, which
+//checkPrivateDataAccess enforces, so the chaincode never admits an org the collections do not or vice versa.
+const (
+	collectionOwnerDetails     = "collectionOwnerDetails"
+	collectionReadingLocations = "collectionReadingLocations"
+)
+
+//locationKeyType - object type of the composite keys location~vehicle~txID of the reading locations
+const locationKeyType = "location~vehicle~txID"
+
+//Transient fields carrying private data and secrets - transient data is not recorded in the transaction, unlike the
+//arguments
+const (
+	transientOwnerDetails      = "ownerDetails"
+	transientLocation          = "location"
+	transientLocations         = "locations"
+	transientPurgeConfirmation = "purgeConfirmation"
+)
+
+//minSaltLength - shortest salt accepted with private data, so its public hash cannot be reversed by guessing
+const minSaltLength = 16
+
+//OwnerDetails - Personal data of the current owner of a vehicle, stored in collectionOwnerDetails under the vehicle
+//ID. The Vehicle holds its hash.
+type OwnerDetails struct {
+	ObjectType string `json:"docType"`
+	VehicleID  string `json:"vehicleID"`
+	OwnerID    string `json:"ownerID"`
+	Name       string `json:"name"`
+	Email      string `json:"email,omitempty"`
+	Phone      string `json:"phone,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Salt       string `json:"salt"`
+}
+
+//ReadingLocation - GPS position of the vehicle when a Reading was taken, stored in collectionReadingLocations under
+//the composite key location~vehicle~txID. The Reading holds its hash.
+type ReadingLocation struct {
+	ObjectType string  `json:"docType"`
+	VehicleID  string  `json:"vehicleID"`
+	TxID       string  `json:"txID"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Salt       string  `json:"salt"`
+}
+
+//ownerDetailsSchema - schema of the OwnerDetails JSON accepted in the transient field ownerDetails
+var ownerDetailsSchema = []fieldSchema{
+	{name: "name", required: true, validate: stringField(checkText(256))},
+	{name: "email", validate: stringField(checkText(256))},
+	{name: "phone", validate: stringField(checkText(64))},
+	{name: "address", validate: stringField(checkText(1024))},
+	{name: "salt", required: true, validate: stringField(checkSalt)},
+	{name: "docType", validate: readOnlyField},
+	{name: "vehicleID", validate: readOnlyField},
+	{name: "ownerID", validate: readOnlyField},
+}
+
+//locationSchema - schema of the ReadingLocation JSON accepted in the transient field location
+var locationSchema = []fieldSchema{
+	{name: "latitude", required: true, validate: numberField(checkRange(-90, 90))},
+	{name: "longitude", required: true, validate: numberField(checkRange(-180, 180))},
+	{name: "salt", required: true, validate: stringField(checkSalt)},
+	{name: "docType", validate: readOnlyField},
+	{name: "vehicleID", validate: readOnlyField},
+	{name: "txID", validate: readOnlyField},
+}
+
+//Query Route: readOwnerDetails - personal data of the current owner of a vehicle, for members of the collections only
+func (rdg *ReadingAsset) readOwnerDetails(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	err := rdg.checkPrivateDataAccess(stub)
+	if err != nil {
+		return errorResponse(withPrefix("readOwnerDetails: ", err))
+	}
+	bytes, err := stub.GetPrivateData(collectionOwnerDetails, vehicleID)
+	if err != nil {
+		return internalError("readOwnerDetails: Error retrieving owner details of vehicle with ID: " + vehicleID)
+	}
+	if bytes == nil {
+		return notFound("readOwnerDetails: No owner details found for vehicle with ID: " + vehicleID)
+	}
+	return shim.Success(bytes)
+}
+
+//Query Route: readReadingLocation - arguments: vehicle ID and optionally the txID of a Reading, else the current
+//Reading. GPS position of the vehicle for members of the collections only.
+func (rdg *ReadingAsset) readReadingLocation(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	var currReading Reading
+	if len(args) < 1 || len(args) > 2 {
+		return badRequest("readReadingLocation: Expects one or two arguments: vehicle ID and txID of the reading")
+	}
+	err := rdg.checkPrivateDataAccess(stub)
+	if err != nil {
+		return errorResponse(withPrefix("readReadingLocation: ", err))
+	}
+	vehicleID := args[0]
+	txID := ""
+	if len(args) == 2 {
+		txID = args[1]
+	} else {
+		readingAsByteArray, err := rdg.retrieveReading(stub, vehicleID)
+		if err != nil {
+			return errorResponse(withPrefix("readReadingLocation: ", err))
+		}
+		err = json.Unmarshal(readingAsByteArray, &currReading)
+		if err != nil {
+			return internalError("readReadingLocation: Error unmarshalling reading JSON")
+		}
+		txID = currReading.TxID
+	}
+	locationKey, err := stub.CreateCompositeKey(locationKeyType, []string{vehicleID, txID})
+	if err != nil {
+		return badRequest("readReadingLocation: Invalid vehicle ID or txID")
+	}
+	bytes, err := stub.GetPrivateData(collectionReadingLocations, locationKey)
+	if err != nil {
+		return internalError("readReadingLocation: Error retrieving location of vehicle with ID: " + vehicleID)
+	}
+	if bytes == nil {
+		return notFound("readReadingLocation: No location found for the reading " + txID + " of vehicle with ID: " + vehicleID)
+	}
+	return shim.Success(bytes)
+}
+
+//Helper: Check the caller's MSP is one of the configured privateDataMSPIDs, which must list the member orgs of the
+//collection policies in collections_config.json
+func (rdg *ReadingAsset) checkPrivateDataAccess(stub shim.ChaincodeStubInterface) error {
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return err
+	}
+	if len(config.PrivateDataMSPIDs) == 0 {
+		return newError(CONFLICT, codeNotConfigured, "No privateDataMSPIDs configured")
+	}
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Unable to get MSP ID of caller")
+	}
+	if !containsString(config.PrivateDataMSPIDs, mspID) {
+		return newError(UNAUTHORIZED, codeUnauthorized, "MSP "+mspID+" is not a member of the private data collections")
+	}
+	return nil
+}
+
+//Helper: Save owner details - replaces the private record of the vehicle's owner
+func (rdg *ReadingAsset) saveOwnerDetails(stub shim.ChaincodeStubInterface, details OwnerDetails) (bool, error) {
+	bytes, err := json.Marshal(details)
+	if err != nil {
+		return false, errors.New("saveOwnerDetails: Error converting owner details JSON")
+	}
+	err = stub.PutPrivateData(collectionOwnerDetails, details.VehicleID, bytes)
+	if err != nil {
+		return false, errors.New("saveOwnerDetails: Error storing owner details")
+	}
+	return true, nil
+}
+
+//Helper: Delete owner details - the personal data of a former owner is not kept
+func (rdg *ReadingAsset) deleteOwnerDetails(stub shim.ChaincodeStubInterface, vehicleID string) (bool, error) {
+	err := stub.DelPrivateData(collectionOwnerDetails, vehicleID)
+	if err != nil {
+		return false, errors.New("deleteOwnerDetails: Error deleting owner details of vehicle with ID: " + vehicleID)
+	}
+	return true, nil
+}
+
+//Helper: Save reading location
+func (rdg *ReadingAsset) saveReadingLocation(stub shim.ChaincodeStubInterface, location ReadingLocation) (bool, error) {
+	locationKey, err := stub.CreateCompositeKey(locationKeyType, []string{location.VehicleID, location.TxID})
+	if err != nil {
+		return false, errors.New("saveReadingLocation: Error creating location key for vehicle with ID: " + location.VehicleID)
+	}
+	bytes, err := json.Marshal(location)
+	if err != nil {
+		return false, errors.New("saveReadingLocation: Error converting reading location JSON")
+	}
+	err = stub.PutPrivateData(collectionReadingLocations, locationKey, bytes)
+	if err != nil {
+		return false, errors.New("saveReadingLocation: Error storing reading location")
+	}
+	return true, nil
+}
+
+//privateDataHash - hex SHA-256 of the stored JSON of a private record, kept on the public ledger
+func privateDataHash(record interface{}) (string, error) {
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		return "", errors.New("Error converting private data JSON")
+	}
+	hash := sha256.Sum256(bytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+//locationHash - hash of location for the Reading, "" without location
+func locationHash(location *ReadingLocation) (string, error) {
+	if location == nil {
+		return "", nil
+	}
+	return privateDataHash(*location)
+}
+
+//getOwnerDetailsFromTransient - owner details of vehicle from the transient field ownerDetails, nil if not sent
+func getOwnerDetailsFromTransient(stub shim.ChaincodeStubInterface, vehicle Vehicle) (*OwnerDetails, error) {
+	input, err := getTransientField(stub, transientOwnerDetails)
+	if err != nil || input == nil {
+		return nil, err
+	}
+	if vehicle.OwnerID == "" {
+		return nil, ValidationError{Fields: []FieldError{{Field: "ownerID", Message: "is required with " + transientOwnerDetails}}}
+	}
+	err = validateInput(string(input), ownerDetailsSchema)
+	if err != nil {
+		return nil, withFieldPrefix(transientOwnerDetails+".", err)
+	}
+	var details OwnerDetails
+	err = json.Unmarshal(input, &details)
+	if err != nil {
+		return nil, err
+	}
+	details.ObjectType = "Private.OwnerDetails"
+	details.VehicleID = vehicle.VehicleID
+	details.OwnerID = vehicle.OwnerID
+	return &details, nil
+}
+
+//getLocationFromTransient - location of the Reading of vehicleID in this transaction from the transient field
+//location, nil if not sent
+func getLocationFromTransient(stub shim.ChaincodeStubInterface, vehicleID string) (*ReadingLocation, error) {
+	input, err := getTransientField(stub, transientLocation)
+	if err != nil || input == nil {
+		return nil, err
+	}
+	return getLocationFromInput(stub, vehicleID, input, transientLocation+".")
+}
+
+//getLocationsFromTransient - locations of the Readings of a batch by vehicle ID from the transient field locations,
+//an empty map if not sent
+func getLocationsFromTransient(stub shim.ChaincodeStubInterface) (map[string]*ReadingLocation, error) {
+	locations := make(map[string]*ReadingLocation)
+	input, err := getTransientField(stub, transientLocations)
+	if err != nil || input == nil {
+		return locations, err
+	}
+	var inputs map[string]json.RawMessage
+	err = json.Unmarshal(input, &inputs)
+	if err != nil || inputs == nil {
+		return locations, ValidationError{Fields: []FieldError{{Field: transientLocations, Message: "must be a JSON object of locations by vehicle ID"}}}
+	}
+	for vehicleID, locationInput := range inputs {
+		locations[vehicleID], err = getLocationFromInput(stub, vehicleID, locationInput, transientLocations+"."+vehicleID+".")
+		if err != nil {
+			return locations, err
+		}
+	}
+	return locations, nil
+}
+
+//getLocationFromInput - validated location JSON for the Reading of vehicleID in this transaction
+func getLocationFromInput(stub shim.ChaincodeStubInterface, vehicleID string, input []byte, fieldPrefix string) (*ReadingLocation, error) {
+	err := validateInput(string(input), locationSchema)
+	if err != nil {
+		return nil, withFieldPrefix(fieldPrefix, err)
+	}
+	var location ReadingLocation
+	err = json.Unmarshal(input, &location)
+	if err != nil {
+		return nil, err
+	}
+	location.ObjectType = "Private.ReadingLocation"
+	location.VehicleID = vehicleID
+	location.TxID = stub.GetTxID()
+	return &location, nil
+}
+
+//getTransientField - value of a field of the transient map, nil if the client did not send it
+func getTransientField(stub shim.ChaincodeStubInterface, name string) ([]byte, error) {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, errors.New("Error getting transient data")
+	}
+	return transient[name], nil
+}
+
+//withFieldPrefix - the ValidationError err with prefix added to its field names, other errors unchanged
+func withFieldPrefix(prefix string, err error) error {
+	validationError, ok := err.(ValidationError)
+	if !ok {
+		return err
+	}
+	fields := make([]FieldError, 0, len(validationError.Fields))
+	for _, field := range validationError.Fields {
+		if field.Field == "$" {
+			field.Field = prefix[:len(prefix)-1]
+		} else {
+			field.Field = prefix + field.Field
+		}
+		fields = append(fields, field)
+	}
+	return ValidationError{Fields: fields}
+}
+
+//checkSalt - salts must be at least minSaltLength characters
+func checkSalt(value string) string {
+	if len(value) < minSaltLength || len(value) > 256 {
+		return "must be " + strconv.Itoa(minSaltLength) + " to 256 characters of random data"
+	}
+	return ""
+}
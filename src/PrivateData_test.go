@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//TestPrivateData_collectionsConfig
+func TestPrivateData_collectionsConfig(t *testing.T) {
+	bytes, err := ioutil.ReadFile("collections_config.json")
+	var collections []struct {
+		Name           string `json:"name"`
+		Policy         string `json:"policy"`
+		MemberOnlyRead bool   `json:"memberOnlyRead"`
+	}
+	if err == nil {
+		err = json.Unmarshal(bytes, &collections)
+	}
+	if err != nil || len(collections) != 2 || collections[0].Name != collectionOwnerDetails ||
+		collections[1].Name != collectionReadingLocations || collections[0].Policy != collections[1].Policy ||
+		!collections[0].MemberOnlyRead || !collections[1].MemberOnlyRead {
+		fmt.Println("Expected both collections with the same member-only policy, Actual:", err, string(bytes))
+		t.FailNow()
+	}
+}
+
+//TestPrivateData_ownerDetails
+func TestPrivateData_ownerDetails(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"privateDataMSPIDs\":[\"Org1MSP\"]"))
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res := invokeWithTransientForTesting(stub, "1", transientOwnerDetails, getOwnerDetailsForTesting("Jane Doe"),
+		getVehicleForTesting("registerVehicle", "100001", getVINForTesting("100001"), "US"))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"registerVehicle: Owner Details are Corrupted: ownerID: is required with ownerDetails")
+	res = invokeWithTransientForTesting(stub, "1", transientOwnerDetails, "{\"name\":\"Jane Doe\",\"salt\":\"short\"}",
+		getOwnerVehicleForTesting("owner-1"))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"registerVehicle: Owner Details are Corrupted: ownerDetails.salt: must be 16 to 256 characters of random data")
+	res = invokeWithTransientForTesting(stub, "1", transientOwnerDetails, getOwnerDetailsForTesting("Jane Doe"), getOwnerVehicleForTesting("owner-1"))
+	if res.Status != shim.OK {
+		fmt.Println("func registerVehicle with owner details failed", res.Message)
+		t.FailNow()
+	}
+	vehicle := checkOwnerDetailsHash(t, stub, "Jane Doe")
+	if strings.Contains(string(stub.State[getVehicleKeyForTesting(stub, "100001")]), "Jane Doe") {
+		fmt.Println("Owner details must not be stored in public state:", string(stub.State[getVehicleKeyForTesting(stub, "100001")]))
+		t.FailNow()
+	}
+	stub.Creator = getCreatorForTesting("Org2MSP", "registry")
+	res = stub.MockInvoke("1", [][]byte{[]byte("readOwnerDetails"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeUnauthorized,
+		"readOwnerDetails: MSP Org2MSP is not a member of the private data collections")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res = invokeWithTransientForTesting(stub, "1", transientOwnerDetails, getOwnerDetailsForTesting("Jane Smith"),
+		getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100001"), "US"))
+	if res.Status != shim.OK {
+		fmt.Println("func updateVehicle with owner details failed", res.Message)
+		t.FailNow()
+	}
+	updated := checkOwnerDetailsHash(t, stub, "Jane Smith")
+	if updated.OwnerDetailsHash == vehicle.OwnerDetailsHash {
+		fmt.Println("func updateVehicle expected a new owner details hash")
+		t.FailNow()
+	}
+	checkInvoke(t, stub, getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100001"), "US"))
+	checkOwnerDetailsHash(t, stub, "Jane Smith")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res = invokeWithTransientForTesting(stub, "1", transientOwnerDetails, getOwnerDetailsForTesting("John Roe"),
+		getTransferForTesting("owner-1", "owner-2", "2017-12-10T10:00:00Z"))
+	if res.Status != shim.OK {
+		fmt.Println("func transferOwnership with owner details failed", res.Message)
+		t.FailNow()
+	}
+	checkOwnerDetailsHash(t, stub, "John Roe")
+	checkInvoke(t, stub, getTransferForTesting("owner-2", "owner-3", "2017-12-11T10:00:00Z"))
+	res = stub.MockInvoke("1", [][]byte{[]byte("readOwnerDetails"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "readOwnerDetails: No owner details found for vehicle with ID: 100001")
+	res = stub.MockInvoke("1", [][]byte{[]byte("readVehicle"), []byte("100001")})
+	if res.Status != shim.OK || strings.Contains(string(res.Payload), "ownerDetailsHash") {
+		fmt.Println("func transferOwnership without owner details expected the hash to be removed, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+}
+
+//TestPrivateData_readingLocation
+func TestPrivateData_readingLocation(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	res := invokeWithTransientForTesting(stub, "1", transientLocation, "{\"latitude\":91,\"longitude\":8.6,\"salt\":\"0123456789abcdef\"}",
+		getFirstReadingAssetForTesting())
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"addNewReading: Location Data is Corrupted: location.latitude: must be between -90 and 90")
+	res = invokeWithTransientForTesting(stub, "1", transientLocation, getLocationForTesting(49.29, 8.64), getFirstReadingAssetForTesting())
+	if res.Status != shim.OK {
+		fmt.Println("func addNewReading with location failed", res.Message)
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReadingLocation"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeNotConfigured, "readReadingLocation: No privateDataMSPIDs configured")
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkInvoke(t, stub, [][]byte{[]byte("updateConfig"), []byte("{\"privateDataMSPIDs\":[\"Org1MSP\"]}")})
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	res = invokeWithTransientForTesting(stub, "tx2", transientLocation, getLocationForTesting(49.3, 8.7), getUpdateReadingAssetForOKTesting())
+	if res.Status != shim.OK {
+		fmt.Println("func updateReading with location failed", res.Message)
+		t.FailNow()
+	}
+	checkReadingLocation(t, stub, []string{"100001"}, 49.3)
+	checkReadingLocation(t, stub, []string{"100001", "1"}, 49.29)
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReadingLocation"), []byte("100002")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "readReadingLocation: retrieveReading: No reading found with ID: 100002")
+}
+
+//TestPrivateData_batchLocations
+func TestPrivateData_batchLocations(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"privateDataMSPIDs\":[\"Org1MSP\"]"))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	batch := getBatchForTesting("bestEffort", string(getFirstReadingAssetForTesting()[1]), string(getSecondReadingAssetForTesting()[1]))
+	res := invokeWithTransientForTesting(stub, "1", transientLocations, "{\"100001\":{\"latitude\":49.29}}", batch)
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed, "addReadingsBatch: Location Data is Corrupted: "+
+		"locations.100001.longitude: is required; locations.100001.salt: is required")
+	res = invokeWithTransientForTesting(stub, "1", transientLocations, "{\"100001\":"+getLocationForTesting(49.29, 8.64)+"}", batch)
+	if res.Status != shim.OK {
+		fmt.Println("func addReadingsBatch with locations failed", res.Message)
+		t.FailNow()
+	}
+	checkReadingLocation(t, stub, []string{"100001"}, 49.29)
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReadingLocation"), []byte("100002")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound, "readReadingLocation: No location found for the reading 1 of vehicle with ID: 100002")
+}
+
+//checkOwnerDetailsHash - helper checking the stored owner details of vehicle 100001 and their hash on the vehicle
+func checkOwnerDetailsHash(t *testing.T, stub *ExtendedMockStub, name string) Vehicle {
+	res := stub.MockInvoke("1", [][]byte{[]byte("readOwnerDetails"), []byte("100001")})
+	var details OwnerDetails
+	err := json.Unmarshal(res.Payload, &details)
+	if res.Status != shim.OK || err != nil || details.Name != name || details.VehicleID != "100001" {
+		fmt.Println("func readOwnerDetails expected owner", name, "Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	var vehicle Vehicle
+	res = stub.MockInvoke("1", [][]byte{[]byte("readVehicle"), []byte("100001")})
+	err = json.Unmarshal(res.Payload, &vehicle)
+	hash := sha256.Sum256(stub.PvtState[collectionOwnerDetails]["100001"])
+	if err != nil || vehicle.OwnerDetailsHash != hex.EncodeToString(hash[:]) {
+		fmt.Println("Vehicle expected the hash of the owner details, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	return vehicle
+}
+
+//checkReadingLocation - helper checking the latitude returned by readReadingLocation with args
+func checkReadingLocation(t *testing.T, stub *ExtendedMockStub, args []string, latitude float64) {
+	invokeArgs := [][]byte{[]byte("readReadingLocation")}
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+	res := stub.MockInvoke("1", invokeArgs)
+	var location ReadingLocation
+	err := json.Unmarshal(res.Payload, &location)
+	if res.Status != shim.OK || err != nil || location.Latitude != latitude {
+		fmt.Println("func readReadingLocation", args, "expected latitude", latitude, "Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	var reading Reading
+	for _, entry := range stub.history["100001"] {
+		if entry.TxId == location.TxID {
+			json.Unmarshal(entry.Value, &reading)
+		}
+	}
+	hash := sha256.Sum256(res.Payload)
+	if reading.LocationHash != hex.EncodeToString(hash[:]) {
+		fmt.Println("Reading of tx", location.TxID, "expected the hash of its location, Actual:", reading.LocationHash)
+		t.FailNow()
+	}
+}
+
+//invokeWithTransientForTesting - invokes args in transaction txID with the transient field name set to value
+func invokeWithTransientForTesting(stub *ExtendedMockStub, txID string, name string, value string, args [][]byte) peer.Response {
+	stub.Transient = map[string][]byte{name: []byte(value)}
+	res := stub.MockInvoke(txID, args)
+	stub.Transient = nil
+	return res
+}
+
+//Get owner details for testing
+func getOwnerDetailsForTesting(name string) string {
+	return "{\"name\":\"" + name + "\",\"email\":\"owner@example.com\",\"salt\":\"3f1c9a0e7b2d4c6f\"}"
+}
+
+//Get a location for testing
+func getLocationForTesting(latitude float64, longitude float64) string {
+	return fmt.Sprintf("{\"latitude\":%g,\"longitude\":%g,\"salt\":\"a8d04c2e9f1b7e35\"}", latitude, longitude)
+}
+
+//Get registerVehicle arguments for vehicle 100001 with an owner for testing
+func getOwnerVehicleForTesting(ownerID string) [][]byte {
+	return [][]byte{[]byte("registerVehicle"),
+		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Vehicle\",\"vin\":\"" + getVINForTesting("100001") +
+			"\",\"make\":\"Ford\",\"model\":\"Focus\",\"year\":2019,\"registrationCountry\":\"US\",\"ownerID\":\"" + ownerID + "\"}")}
+}
+
+//Get the state key of a vehicle for testing
+func getVehicleKeyForTesting(stub *ExtendedMockStub, vehicleID string) string {
+	key, _ := stub.CreateCompositeKey(vehicleKeyType, []string{vehicleID})
+	return key
+}
@@ -8,25 +8,93 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/hyperledger/fabric/protos/peer"
 )
 
 var logger = shim.NewLogger("CLDChaincode")
 
+//readingHistoryKeyType - object type of the composite keys holding the reading history
+const readingHistoryKeyType = "vehicle~seq"
+
+//sequenceKeyType - object type of the composite keys holding the number of records of a vehicle per record key type
+const sequenceKeyType = "sequence~type~vehicle"
+
+//readingIDIndexKeyType - object type of the composite keys indexing the IDs of all Readings
+const readingIDIndexKeyType = "readingIDIndex~vehicleID"
+
+//purgeKeyType - object type of the composite keys holding the purge records
+const purgeKeyType = "purge~txID"
+
+//defaultPageSize, maxPageSize - bounds for paginated queries, keeping responses below the peer's message size limit
+const defaultPageSize = 100
+const maxPageSize = 1000
+
+//legacyReadingIDIndexKey - key of the former monolithic ID array, migrated by Init
+const legacyReadingIDIndexKey = "readingIDIndex"
+
 //ReadingAsset - Chaincode for asset Reading
 type ReadingAsset struct {
 }
 
-//Reading - Details of the asset type Reading
+//Reading - Details of the asset type Reading. Its position is the private ReadingLocation; the public ledger holds
+//only the location hash and a coarse geohash of it.
 type Reading struct {
-	VehicleID    string `json:"vehicleID"`
-	ObjectType   string `json:"docType"`
-	Reading      string `json:"reading"`
-	CreationDate string `json:"creationDate"`
+	VehicleID        string        `json:"vehicleID"`
+	ObjectType       string        `json:"docType"`
+	Reading          OdometerValue `json:"reading"`
+	Unit             string        `json:"unit"`
+	CreationDate     string        `json:"creationDate"`
+	Geohash          string        `json:"geohash,omitempty"`
+	Source           string        `json:"source,omitempty"`
+	SourceRef        string        `json:"sourceRef,omitempty"`
+	Confidence       string        `json:"confidence,omitempty"`
+	SignedBy         string        `json:"signedBy,omitempty"`
+	SignedCounter    uint64        `json:"signedCounter,omitempty"`
+	OdometerOffset   OdometerValue `json:"odometerOffset,omitempty"`
+	TrueMileage      OdometerValue `json:"trueMileage,omitempty"`
+	Flags            []string      `json:"flags,omitempty"`
+	LocationHash     string        `json:"locationHash,omitempty"`
+	SubmitterMSPID   string        `json:"submitterMSPID"`
+	SubmitterSubject string        `json:"submitterSubject"`
+	TxID             string        `json:"txID"`
+	TxTimestamp      string        `json:"txTimestamp"`
+}
+
+//ReadingHistoryEntry - One accepted Reading of a vehicle, stored under the composite key vehicle~seq
+type ReadingHistoryEntry struct {
+	ObjectType string  `json:"docType"`
+	VehicleID  string  `json:"vehicleID"`
+	Sequence   int     `json:"seq"`
+	Reading    Reading `json:"record"`
+}
+
+//ReadingAuditEntry - One version of a Reading as kept by the peer's history database
+type ReadingAuditEntry struct {
+	TxID      string   `json:"txID"`
+	Timestamp string   `json:"timestamp"`
+	IsDelete  bool     `json:"isDelete"`
+	Reading   *Reading `json:"reading"`
 }
 
-//ReadingIDIndex - Index on IDs for retrieval all Readings
+//ReadingPage - One page of Readings and the bookmark to pass for the next page ("" on the last page)
+type ReadingPage struct {
+	Records      []Reading `json:"records"`
+	FetchedCount int32     `json:"fetchedCount"`
+	Bookmark     string    `json:"bookmark"`
+}
+
+//PurgeRecord - Who archived all readings and when
+type PurgeRecord struct {
+	ObjectType string    `json:"docType"`
+	TxID       string    `json:"txID"`
+	Timestamp  string    `json:"timestamp"`
+	PurgedBy   Submitter `json:"purgedBy"`
+	VehicleIDs []string  `json:"vehicleIDs"`
+}
+
+//ReadingIDIndex - Legacy index on IDs, kept as one JSON array under the key "readingIDIndex" - only read for migration
 type ReadingIDIndex struct {
 	VehicleIDs []string `json:"vehicleIDs"`
 }
@@ -40,11 +108,17 @@ func main() {
 	}
 }
 
-//Init - The chaincode Init function: No  arguments, only initializes a ID array as Index for retrieval of all Readings
+//Init - The chaincode Init function: optional configuration JSON, migrates a legacy readingIDIndex array to composite keys on upgrade
 func (rdg *ReadingAsset) Init(stub shim.ChaincodeStubInterface) peer.Response {
-	var readingIDIndex ReadingIDIndex
-	bytes, _ := json.Marshal(readingIDIndex)
-	stub.PutState("readingIDIndex", bytes)
+	_, args := stub.GetFunctionAndParameters()
+	_, err := rdg.initConfig(stub, args)
+	if err != nil {
+		return errorResponse(err)
+	}
+	_, err = rdg.migrateReadingIDIndex(stub)
+	if err != nil {
+		return errorResponse(err)
+	}
 	return shim.Success(nil)
 }
 
@@ -56,99 +130,376 @@ func (rdg *ReadingAsset) Invoke(stub shim.ChaincodeStubInterface) peer.Response
 		return rdg.addNewReading(stub, args)
 	} else if function == "updateReading" {
 		return rdg.updateReading(stub, args)
+	} else if function == "addReadingsBatch" {
+		return rdg.addReadingsBatch(stub, args)
 	} else if function == "removeAllReadings" {
-		return rdg.removeAllReadings(stub)
+		return rdg.removeAllReadings(stub, args)
 	} else if function == "readReading" {
+		if len(args) != 1 {
+			return badRequest("readReading: Expects exactly one argument: vehicle ID")
+		}
 		return rdg.readReading(stub, args[0])
 	} else if function == "readAllReadings" {
-		return rdg.readAllReadings(stub)
+		return rdg.readAllReadings(stub, args)
+	} else if function == "readReadingHistory" {
+		if len(args) != 1 && len(args) != 2 {
+			return badRequest("readReadingHistory: Expects one or two arguments: vehicle ID and optional source")
+		}
+		source := ""
+		if len(args) == 2 {
+			source = args[1]
+		}
+		return rdg.readReadingHistory(stub, args[0], source)
+	} else if function == "getReadingAudit" {
+		if len(args) != 1 {
+			return badRequest("getReadingAudit: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.getReadingAudit(stub, args[0])
+	} else if function == "queryReadings" {
+		return rdg.queryReadings(stub, args)
+	} else if function == "queryReadingsNear" {
+		return rdg.queryReadingsNear(stub, args)
+	} else if function == "updateConfig" {
+		return rdg.updateConfig(stub, args)
+	} else if function == "registerVehicle" {
+		return rdg.registerVehicle(stub, args)
+	} else if function == "updateVehicle" {
+		return rdg.updateVehicle(stub, args)
+	} else if function == "readVehicle" {
+		if len(args) != 1 {
+			return badRequest("readVehicle: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.readVehicle(stub, args[0])
+	} else if function == "transferOwnership" {
+		return rdg.transferOwnership(stub, args)
+	} else if function == "readOwnershipChain" {
+		if len(args) != 1 {
+			return badRequest("readOwnershipChain: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.readOwnershipChain(stub, args[0])
+	} else if function == "issueMileageCertificate" {
+		if len(args) != 1 {
+			return badRequest("issueMileageCertificate: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.issueMileageCertificate(stub, args[0])
+	} else if function == "verifyMileageCertificate" {
+		if len(args) != 1 {
+			return badRequest("verifyMileageCertificate: Expects exactly one argument: certificate ID")
+		}
+		return rdg.verifyMileageCertificate(stub, args[0])
+	} else if function == "revokeMileageCertificate" {
+		return rdg.revokeMileageCertificate(stub, args)
+	} else if function == "readSuspiciousReadings" {
+		if len(args) != 1 {
+			return badRequest("readSuspiciousReadings: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.readSuspiciousReadings(stub, args[0])
+	} else if function == "readOwnerDetails" {
+		if len(args) != 1 {
+			return badRequest("readOwnerDetails: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.readOwnerDetails(stub, args[0])
+	} else if function == "readReadingLocation" {
+		return rdg.readReadingLocation(stub, args)
+	} else if function == "recordOdometerReplacement" {
+		return rdg.recordOdometerReplacement(stub, args)
+	} else if function == "readOdometerReplacements" {
+		if len(args) != 1 {
+			return badRequest("readOdometerReplacements: Expects exactly one argument: vehicle ID")
+		}
+		return rdg.readOdometerReplacements(stub, args[0])
+	} else if function == "registerDevice" {
+		return rdg.registerDevice(stub, args)
+	} else if function == "updateDevice" {
+		return rdg.updateDevice(stub, args)
+	} else if function == "readDevice" {
+		if len(args) != 1 {
+			return badRequest("readDevice: Expects exactly one argument: device ID")
+		}
+		return rdg.readDevice(stub, args[0])
 	}
-	return shim.Error("Received unknown function invocation")
+	return errorResponse(newError(BADREQUEST, codeUnknownFunction, "Received unknown function invocation"))
 }
 
-//Invoke Route: addNewReading
+//Invoke Route: addNewReading - argument: Reading JSON or SignedReading envelope. The transient field location may
+//carry the GPS position of the vehicle, which is stored in collectionReadingLocations
 func (rdg *ReadingAsset) addNewReading(stub shim.ChaincodeStubInterface, args []string) peer.Response {
-	reading, err := getReadingFromArgs(args)
+	err := rdg.checkAccess(stub, readingRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("addNewReading: ", err))
+	}
+	reading, err := rdg.getReadingInput(stub, args)
+	if err != nil {
+		return errorResponse(err)
+	}
+	location, err := getLocationFromTransient(stub, reading.VehicleID)
+	if err != nil {
+		return validationFailed("addNewReading: Location Data is Corrupted", err)
+	}
+	err = locateReading(&reading, location)
+	if err != nil {
+		return errorResponse(withPrefix("addNewReading: ", err))
+	}
+	reading, err = rdg.storeNewReading(stub, reading)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if location != nil {
+		_, err = rdg.saveReadingLocation(stub, *location)
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+	err = setReadingEvent(stub, eventReadingAdded, Reading{}, reading, "")
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+//Invoke Route: updateReading - argument: Reading JSON or SignedReading envelope. The transient field location may
+//carry the GPS position of the vehicle, as for addNewReading
+func (rdg *ReadingAsset) updateReading(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, readingRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("updateReading: ", err))
+	}
+	newReading, err := rdg.getReadingInput(stub, args)
+	if err != nil {
+		return errorResponse(withPrefix("updateReading: ", err))
+	}
+	location, err := getLocationFromTransient(stub, newReading.VehicleID)
+	if err != nil {
+		return validationFailed("updateReading: Location Data is Corrupted", err)
+	}
+	err = locateReading(&newReading, location)
+	if err != nil {
+		return errorResponse(withPrefix("updateReading: ", err))
+	}
+	currReading, newReading, err := rdg.storeReadingUpdate(stub, newReading)
+	if err != nil {
+		if reason, rejected := rejectionReason(err); rejected {
+			return rdg.rejectReading(stub, currReading, newReading, reason, err.(ChaincodeError))
+		}
+		return errorResponse(err)
+	}
+	if location != nil {
+		_, err = rdg.saveReadingLocation(stub, *location)
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+	err = setReadingEvent(stub, eventReadingUpdated, currReading, newReading, strings.Join(newReading.Flags, ", "))
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+//Helper: Store new reading - checks the first reading of a vehicle and stores it with its history and index
+//entries, returns the stored reading. After removeAllReadings it is checked against the last reading of the history
+//like an update.
+func (rdg *ReadingAsset) storeNewReading(stub shim.ChaincodeStubInterface, reading Reading) (Reading, error) {
+	err := rdg.checkReadingSource(stub, &reading)
+	if err != nil {
+		return reading, withPrefix("addNewReading: ", err)
+	}
+	err = rdg.checkReadingTime(stub, reading.CreationDate)
+	if err != nil {
+		return reading, withPrefix("addNewReading: ", err)
+	}
+	_, err = rdg.retrieveVehicle(stub, reading.VehicleID)
 	if err != nil {
-		return shim.Error("Reading Data is Corrupted")
+		return reading, withPrefix("addNewReading: ", err)
 	}
 	reading.ObjectType = "Asset.Reading"
 	record, err := stub.GetState(reading.VehicleID)
 	if record != nil {
-		return shim.Error("This Reading already exists: " + reading.VehicleID)
+		return reading, newError(CONFLICT, codeAlreadyExists, "This Reading already exists: "+reading.VehicleID)
+	}
+	seq, err := lastSequence(stub, readingHistoryKeyType, reading.VehicleID)
+	if err != nil {
+		return reading, err
+	}
+	if seq > 0 {
+		lastReading, err := rdg.retrieveReadingHistoryEntry(stub, reading.VehicleID, seq)
+		if err != nil {
+			return reading, err
+		}
+		err = rdg.checkReadingProgress(stub, lastReading, &reading)
+		if err != nil {
+			return reading, withPrefix("addNewReading: ", err)
+		}
+		err = rdg.checkSourceOverride(stub, reading)
+		if err != nil {
+			return reading, withPrefix("addNewReading: ", err)
+		}
+	}
+	err = stampReading(stub, &reading)
+	if err != nil {
+		return reading, withPrefix("addNewReading: ", err)
 	}
 	_, err = rdg.saveReading(stub, reading)
 	if err != nil {
-		return shim.Error(err.Error())
+		return reading, err
+	}
+	seq, err = rdg.appendReadingHistory(stub, reading)
+	if err != nil {
+		return reading, err
 	}
 	_, err = rdg.updateReadingIDIndex(stub, reading)
 	if err != nil {
-		return shim.Error(err.Error())
+		return reading, err
+	}
+	_, err = rdg.updateGeohashIndex(stub, reading, seq)
+	if err != nil {
+		return reading, err
 	}
-	return shim.Success(nil)
+	_, err = rdg.saveSignedCounter(stub, reading)
+	if err != nil {
+		return reading, err
+	}
+	return reading, nil
 }
 
-//Invoke Route: updateReading
-func (rdg *ReadingAsset) updateReading(stub shim.ChaincodeStubInterface, args []string) peer.Response {
-
+//Helper: Store reading update - checks newReading against the current reading of the vehicle and stores it with
+//its history entry, returns the current and the stored reading. Rejections have a code of rejectionReasons.
+func (rdg *ReadingAsset) storeReadingUpdate(stub shim.ChaincodeStubInterface, newReading Reading) (Reading, Reading, error) {
 	var currReading Reading
-	newReading, err := getReadingFromArgs(args)
+	err := rdg.checkReadingSource(stub, &newReading)
+	if err != nil {
+		return currReading, newReading, withPrefix("updateReading: ", err)
+	}
+	err = rdg.checkReadingTime(stub, newReading.CreationDate)
+	if err != nil {
+		return currReading, newReading, withPrefix("updateReading: ", err)
+	}
 	readingAsByteArray, err := rdg.retrieveReading(stub, newReading.VehicleID)
 	if err != nil {
-		return shim.Error(err.Error())
+		return currReading, newReading, err
 	}
 	err = json.Unmarshal(readingAsByteArray, &currReading)
 	if err != nil {
-		return shim.Error("updateReading: Error unmarshalling readingStruct array JSON")
-	}
-	currReadingVal, _ := strconv.ParseFloat(currReading.Reading, 64)
-	newReadingVal, _ := strconv.ParseFloat(newReading.Reading, 64)
-	if newReadingVal < currReadingVal {
-		return shim.Error("updateReading: New Reading is less than Current Reading - cannot update")
+		return currReading, newReading, errors.New("updateReading: Error unmarshalling readingStruct array JSON")
 	}
-	currDate, err := time.Parse("01/02/2006", currReading.CreationDate)
+	err = rdg.checkReadingProgress(stub, currReading, &newReading)
 	if err != nil {
-		return shim.Error(err.Error())
+		return currReading, newReading, withPrefix("updateReading: ", err)
 	}
-	newDate, err := time.Parse("01/02/2006", newReading.CreationDate)
+	err = rdg.checkSourceOverride(stub, newReading)
 	if err != nil {
-		return shim.Error(err.Error())
+		return currReading, newReading, withPrefix("updateReading: ", err)
 	}
-	if currDate.After(newDate) {
-		return shim.Error("updateReading: New Date is earlier than Current Date - cannot update")
+	err = stampReading(stub, &newReading)
+	if err != nil {
+		return currReading, newReading, withPrefix("updateReading: ", err)
 	}
 	_, err = rdg.saveReading(stub, newReading)
 	if err != nil {
-		return shim.Error(err.Error())
+		return currReading, newReading, err
 	}
-	return shim.Success(nil)
+	seq, err := rdg.appendReadingHistory(stub, newReading)
+	if err != nil {
+		return currReading, newReading, err
+	}
+	_, err = rdg.updateGeohashIndex(stub, newReading, seq)
+	if err != nil {
+		return currReading, newReading, err
+	}
+	_, err = rdg.saveSignedCounter(stub, newReading)
+	if err != nil {
+		return currReading, newReading, err
+	}
+	return currReading, newReading, nil
 }
 
-//Invoke Route: removeAllReadings
-func (rdg *ReadingAsset) removeAllReadings(stub shim.ChaincodeStubInterface) peer.Response {
-	var readingStructIDs ReadingIDIndex
-	bytes, err := stub.GetState("readingIDIndex")
+//Helper: Check reading progress - newReading carries the odometer offset of currReading and must not be less or
+//earlier than it; sets the true mileage and the plausibility flags of newReading
+func (rdg *ReadingAsset) checkReadingProgress(stub shim.ChaincodeStubInterface, currReading Reading, newReading *Reading) error {
+	newReading.OdometerOffset = currReading.OdometerOffset
+	setTrueMileage(newReading)
+	if isMileageRollback(currReading, *newReading) {
+		return newError(CONFLICT, codeRollbackDetected, "New Reading is less than Current Reading - cannot update")
+	}
+	currDate, err := parseReadingTime(currReading.CreationDate)
 	if err != nil {
-		return shim.Error("removeAllReadings: Error getting readingIDIndex array")
+		return err
 	}
-	err = json.Unmarshal(bytes, &readingStructIDs)
+	newDate, err := parseReadingTime(newReading.CreationDate)
 	if err != nil {
-		return shim.Error("removeAllReadings: Error unmarshalling readingIDIndex array JSON")
+		return err
 	}
-	if len(readingStructIDs.VehicleIDs) == 0 {
-		return shim.Error("removeAllReadings: No readings to remove")
+	if currDate.After(newDate) {
+		return newError(CONFLICT, codeDateRegression, "New Date is earlier than Current Date - cannot update")
+	}
+	newReading.Flags, err = rdg.checkPlausibility(stub, currReading, *newReading)
+	return err
+}
+
+//Invoke Route: removeAllReadings - no arguments, the confirmation token set by Init is sent in the transient field
+//purgeConfirmation and cleared by the purge. Soft archive: the current Readings and their index entries, including the
+//geohash index, are removed, the vehicle~seq history of every vehicle is kept
+func (rdg *ReadingAsset) removeAllReadings(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, adminRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("removeAllReadings: ", err))
 	}
-	for _, readingStructID := range readingStructIDs.VehicleIDs {
-		_, err = rdg.deleteReading(stub, readingStructID)
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return errorResponse(withPrefix("removeAllReadings: ", err))
+	}
+	if len(args) != 0 {
+		return badRequest("removeAllReadings: Expects no arguments - the confirmation token is sent in the transient field " +
+			transientPurgeConfirmation)
+	}
+	if config.PurgeConfirmationHash == "" {
+		return conflict(codeNotConfigured, "removeAllReadings: No confirmation token configured - Init sets a new one after each purge")
+	}
+	confirmation, err := getTransientField(stub, transientPurgeConfirmation)
+	if err != nil {
+		return errorResponse(withPrefix("removeAllReadings: ", err))
+	}
+	if confirmation == nil || hashConfirmation(string(confirmation)) != config.PurgeConfirmationHash {
+		return unauthorized("removeAllReadings: Confirmation token does not match")
+	}
+	readingIDs, err := rdg.retrieveReadingIDs(stub)
+	if err != nil {
+		return errorResponse(withPrefix("removeAllReadings: ", err))
+	}
+	if len(readingIDs) == 0 {
+		return notFound("removeAllReadings: No readings to remove")
+	}
+	for _, readingID := range readingIDs {
+		_, err = rdg.deleteReading(stub, readingID)
 		if err != nil {
-			return shim.Error("Failed to remove Reading with ID: " + readingStructID)
+			return internalError("Failed to remove Reading with ID: " + readingID)
 		}
-		_, err = rdg.deleteReadingIDIndex(stub, readingStructID)
+		_, err = rdg.deleteReadingIDIndex(stub, readingID)
 		if err != nil {
-			return shim.Error(err.Error())
+			return errorResponse(err)
 		}
 	}
-	rdg.initHolder(stub)
+	_, err = rdg.deleteGeohashIndex(stub)
+	if err != nil {
+		return errorResponse(err)
+	}
+	purge, err := rdg.savePurgeRecord(stub, readingIDs)
+	if err != nil {
+		return errorResponse(err)
+	}
+	config.PurgeConfirmationHash = ""
+	_, err = rdg.saveConfig(stub, config)
+	if err != nil {
+		return errorResponse(err)
+	}
+	bytes, err := json.Marshal(purge)
+	if err != nil {
+		return internalError("removeAllReadings: Error marshalling purge record JSON")
+	}
+	err = stub.SetEvent(eventReadingsPurged, bytes)
+	if err != nil {
+		return internalError("removeAllReadings: Error setting ReadingsPurged event")
+	}
 	return shim.Success(nil)
 }
 
@@ -156,39 +507,158 @@ func (rdg *ReadingAsset) removeAllReadings(stub shim.ChaincodeStubInterface) pee
 func (rdg *ReadingAsset) readReading(stub shim.ChaincodeStubInterface, readingID string) peer.Response {
 	readingAsByteArray, err := rdg.retrieveReading(stub, readingID)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errorResponse(err)
 	}
 	return shim.Success(readingAsByteArray)
 }
 
-//Query Route: readAllReadings
-func (rdg *ReadingAsset) readAllReadings(stub shim.ChaincodeStubInterface) peer.Response {
-	var readingIDs ReadingIDIndex
-	bytes, err := stub.GetState("readingIDIndex")
+//Query Route: getReadingAudit - every version of the Reading stored under the vehicleID, newest first
+func (rdg *ReadingAsset) getReadingAudit(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	iterator, err := stub.GetHistoryForKey(vehicleID)
 	if err != nil {
-		return shim.Error("readAllReadings: Error getting readingIDIndex array")
+		return internalError("getReadingAudit: Error retrieving history for reading with ID: " + vehicleID)
 	}
-	err = json.Unmarshal(bytes, &readingIDs)
+	defer iterator.Close()
+	entries := []ReadingAuditEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return internalError("getReadingAudit: Error iterating history for reading with ID: " + vehicleID)
+		}
+		entry := ReadingAuditEntry{
+			TxID:     modification.TxId,
+			IsDelete: modification.IsDelete,
+		}
+		txTime, err := ptypes.Timestamp(modification.Timestamp)
+		if err == nil {
+			entry.Timestamp = txTime.Format(time.RFC3339Nano)
+		}
+		if !modification.IsDelete {
+			var reading Reading
+			err = json.Unmarshal(modification.Value, &reading)
+			if err != nil {
+				return internalError("getReadingAudit: Corrupt reading record " + string(modification.Value))
+			}
+			entry.Reading = &reading
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return notFound("getReadingAudit: No history found for reading with ID: " + vehicleID)
+	}
+	bytes, err := json.Marshal(entries)
 	if err != nil {
-		return shim.Error("readAllReadings: Error unmarshalling readingIDIndex array JSON")
+		return internalError("getReadingAudit: Error marshalling reading audit JSON")
 	}
-	result := "["
+	return shim.Success(bytes)
+}
 
-	var readingAsByteArray []byte
+//Query Route: readAllReadings - optional arguments: page size, bookmark returned by the previous page
+func (rdg *ReadingAsset) readAllReadings(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	pageSize, bookmark, err := getPaginationFromArgs(args)
+	if err != nil {
+		return badRequest("readAllReadings: " + err.Error())
+	}
+	iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(readingIDIndexKeyType, []string{}, pageSize, bookmark)
+	if err != nil {
+		return internalError("readAllReadings: Error getting readingIDIndex from state")
+	}
+	defer iterator.Close()
+	page := ReadingPage{Records: []Reading{}}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return internalError("readAllReadings: Error iterating readingIDIndex")
+		}
+		_, keyParts, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || len(keyParts) != 1 {
+			return internalError("readAllReadings: Corrupt readingIDIndex key " + kv.Key)
+		}
+		var reading Reading
+		readingAsByteArray, err := rdg.retrieveReading(stub, keyParts[0])
+		if err == nil {
+			err = json.Unmarshal(readingAsByteArray, &reading)
+		}
+		if err != nil {
+			return internalError("Failed to retrieve reading with ID: " + keyParts[0])
+		}
+		page.Records = append(page.Records, reading)
+	}
+	page.FetchedCount = metadata.FetchedRecordsCount
+	page.Bookmark = metadata.Bookmark
+	bytes, err := json.Marshal(page)
+	if err != nil {
+		return internalError("readAllReadings: Error marshalling reading page JSON")
+	}
+	return shim.Success(bytes)
+}
 
-	for _, readingID := range readingIDs.VehicleIDs {
-		readingAsByteArray, err = rdg.retrieveReading(stub, readingID)
+//Query Route: queryReadings - arguments: CouchDB Mango selector, optional page size and bookmark
+func (rdg *ReadingAsset) queryReadings(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	if len(args) < 1 {
+		return badRequest("queryReadings: Missing selector")
+	}
+	var selector map[string]interface{}
+	err := json.Unmarshal([]byte(args[0]), &selector)
+	if err != nil || selector == nil {
+		return badRequest("queryReadings: Selector is not a valid JSON object")
+	}
+	pageSize, bookmark, err := getPaginationFromArgs(args[1:])
+	if err != nil {
+		return badRequest("queryReadings: " + err.Error())
+	}
+	selector["docType"] = "Asset.Reading"
+	queryString, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return internalError("queryReadings: Error marshalling query JSON")
+	}
+	iterator, metadata, err := stub.GetQueryResultWithPagination(string(queryString), pageSize, bookmark)
+	if err != nil {
+		return internalError("queryReadings: Error executing query " + err.Error())
+	}
+	defer iterator.Close()
+	page := ReadingPage{Records: []Reading{}}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
 		if err != nil {
-			return shim.Error("Failed to retrieve reading with ID: " + readingID)
+			return internalError("queryReadings: Error iterating query result")
 		}
-		result += string(readingAsByteArray) + ","
+		var reading Reading
+		err = json.Unmarshal(kv.Value, &reading)
+		if err != nil {
+			return internalError("queryReadings: Corrupt reading record " + string(kv.Value))
+		}
+		page.Records = append(page.Records, reading)
 	}
-	if len(result) == 1 {
-		result = "[]"
-	} else {
-		result = result[:len(result)-1] + "]"
+	page.FetchedCount = metadata.FetchedRecordsCount
+	page.Bookmark = metadata.Bookmark
+	bytes, err := json.Marshal(page)
+	if err != nil {
+		return internalError("queryReadings: Error marshalling reading page JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//Query Route: readReadingHistory - all accepted Readings of a vehicle, oldest first; only those of source if set
+func (rdg *ReadingAsset) readReadingHistory(stub shim.ChaincodeStubInterface, vehicleID string, source string) peer.Response {
+	if source != "" && !containsString(sources, source) {
+		return badRequest("readReadingHistory: Unknown source " + source + " - expects one of: " + strings.Join(sources, ", "))
+	}
+	readings, err := rdg.retrieveReadingHistory(stub, vehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if len(readings) == 0 {
+		return notFound("readReadingHistory: No readings found for vehicle with ID: " + vehicleID)
+	}
+	if source != "" {
+		readings = filterReadingsBySource(readings, source)
+	}
+	bytes, err := json.Marshal(readings)
+	if err != nil {
+		return internalError("readReadingHistory: Error marshalling reading history JSON")
 	}
-	return shim.Success([]byte(result))
+	return shim.Success(bytes)
 }
 
 //Helper: Save purchaser
@@ -217,75 +687,293 @@ func (rdg *ReadingAsset) deleteReading(stub shim.ChaincodeStubInterface, reading
 	return true, nil
 }
 
-//Helper: Update reading Holder - updates Index
+//Helper: Update reading Holder - adds one composite key per reading ID, so writes for different vehicles don't conflict
 func (rdg *ReadingAsset) updateReadingIDIndex(stub shim.ChaincodeStubInterface, reading Reading) (bool, error) {
-	var readingIDs ReadingIDIndex
-	bytes, err := stub.GetState("readingIDIndex")
+	indexKey, err := stub.CreateCompositeKey(readingIDIndexKeyType, []string{reading.VehicleID})
 	if err != nil {
-		return false, errors.New("updateReadingIDIndex: Error getting readingIDIndex array Index from state")
+		return false, errors.New("updateReadingIDIndex: Error creating index key for reading ID: " + reading.VehicleID)
 	}
-	err = json.Unmarshal(bytes, &readingIDs)
+	err = stub.PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return false, errors.New("updateReadingIDIndex: Error storing new reading ID in readingIDIndex (Index)")
+	}
+	return true, nil
+}
+
+//Helper: delete ID from readingStruct Holder
+func (rdg *ReadingAsset) deleteReadingIDIndex(stub shim.ChaincodeStubInterface, readingID string) (bool, error) {
+	indexKey, err := stub.CreateCompositeKey(readingIDIndexKeyType, []string{readingID})
 	if err != nil {
-		return false, errors.New("updateReadingIDIndex: Error unmarshalling readingIDIndex array JSON")
+		return false, errors.New("deleteReadingIDIndex: Error creating index key for reading ID: " + readingID)
 	}
-	readingIDs.VehicleIDs = append(readingIDs.VehicleIDs, reading.VehicleID)
-	bytes, err = json.Marshal(readingIDs)
+	bytes, err := stub.GetState(indexKey)
 	if err != nil {
-		return false, errors.New("updateReadingIDIndex: Error marshalling new reading ID")
+		return false, errors.New("deleteReadingIDIndex: Error getting reading ID from readingIDIndex (Index)")
 	}
-	err = stub.PutState("readingIDIndex", bytes)
+	if bytes == nil {
+		return false, errors.New("Specified Key: " + readingID + " not found in Index")
+	}
+	err = stub.DelState(indexKey)
 	if err != nil {
-		return false, errors.New("updateReadingIDIndex: Error storing new reading ID in readingIDIndex (Index)")
+		return false, errors.New("deleteReadingIDIndex: Error deleting reading ID from readingIDIndex (Index)")
 	}
 	return true, nil
 }
 
-//Helper: delete ID from readingStruct Holder
-func (rdg *ReadingAsset) deleteReadingIDIndex(stub shim.ChaincodeStubInterface, readingID string) (bool, error) {
-	var readingStructIDs ReadingIDIndex
-	bytes, err := stub.GetState("readingIDIndex")
+//Helper: Retrieve all reading IDs - range scan over the readingIDIndex composite keys
+func (rdg *ReadingAsset) retrieveReadingIDs(stub shim.ChaincodeStubInterface) ([]string, error) {
+	readingIDs := []string{}
+	iterator, err := stub.GetStateByPartialCompositeKey(readingIDIndexKeyType, []string{})
 	if err != nil {
-		return false, errors.New("deleteReadingIDIndex: Error getting readingIDIndex array Index from state")
+		return readingIDs, errors.New("Error getting readingIDIndex from state")
 	}
-	err = json.Unmarshal(bytes, &readingStructIDs)
+	defer iterator.Close()
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return readingIDs, errors.New("Error iterating readingIDIndex")
+		}
+		_, keyParts, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || len(keyParts) != 1 {
+			return readingIDs, errors.New("Corrupt readingIDIndex key " + kv.Key)
+		}
+		readingIDs = append(readingIDs, keyParts[0])
+	}
+	return readingIDs, nil
+}
+
+//Helper: Migrate the legacy readingIDIndex array (if any) to composite keys and remove it. The history of a legacy
+//reading is started with the reading, unless the vehicle has one.
+func (rdg *ReadingAsset) migrateReadingIDIndex(stub shim.ChaincodeStubInterface) (bool, error) {
+	var legacyIndex ReadingIDIndex
+	bytes, err := stub.GetState(legacyReadingIDIndexKey)
+	if err != nil {
+		return false, errors.New("migrateReadingIDIndex: Error getting readingIDIndex array from state")
+	}
+	if bytes == nil {
+		return false, nil
+	}
+	err = json.Unmarshal(bytes, &legacyIndex)
+	if err != nil {
+		return false, errors.New("migrateReadingIDIndex: Error unmarshalling readingIDIndex array JSON")
+	}
+	for _, vehicleID := range legacyIndex.VehicleIDs {
+		_, err = rdg.updateReadingIDIndex(stub, Reading{VehicleID: vehicleID})
+		if err != nil {
+			return false, err
+		}
+		_, err = rdg.migrateReadingHistory(stub, vehicleID)
+		if err != nil {
+			return false, err
+		}
+	}
+	err = stub.DelState(legacyReadingIDIndexKey)
 	if err != nil {
-		return false, errors.New("deleteReadingIDIndex: Error unmarshalling readingIDIndex array JSON")
+		return false, errors.New("migrateReadingIDIndex: Error deleting readingIDIndex array")
+	}
+	logger.Info("migrateReadingIDIndex: migrated ", len(legacyIndex.VehicleIDs), " reading IDs to composite keys")
+	return true, nil
+}
+
+//Helper: Migrate the reading of a vehicle without history - appends the stored reading as its first history entry
+func (rdg *ReadingAsset) migrateReadingHistory(stub shim.ChaincodeStubInterface, vehicleID string) (bool, error) {
+	seq, err := lastSequence(stub, readingHistoryKeyType, vehicleID)
+	if err != nil || seq > 0 {
+		return false, err
 	}
-	readingStructIDs.VehicleIDs, err = deleteKeyFromStringArray(readingStructIDs.VehicleIDs, readingID)
+	bytes, err := stub.GetState(vehicleID)
 	if err != nil {
-		return false, errors.New(err.Error())
+		return false, errors.New("migrateReadingHistory: Error retrieving reading with ID: " + vehicleID)
+	}
+	if bytes == nil {
+		return false, nil
 	}
-	bytes, err = json.Marshal(readingStructIDs)
+	var reading Reading
+	err = json.Unmarshal(bytes, &reading)
 	if err != nil {
-		return false, errors.New("deleteReadingIDIndex: Error marshalling new readingStruct ID")
+		return false, errors.New("migrateReadingHistory: Corrupt reading record " + string(bytes))
 	}
-	err = stub.PutState("readingIDIndex", bytes)
+	_, err = rdg.appendReadingHistory(stub, reading)
 	if err != nil {
-		return false, errors.New("deleteReadingIDIndex: Error storing new readingStruct ID in readingIDIndex (Index)")
+		return false, err
 	}
 	return true, nil
 }
 
-//Helper: Initialize truck ID Holder //change template
-func (rdg *ReadingAsset) initHolder(stub shim.ChaincodeStubInterface) bool {
-	var readingIDIndex ReadingIDIndex
-	bytes, _ := json.Marshal(readingIDIndex)
-	stub.DelState("readingIDIndex")
-	stub.PutState("readingIDIndex", bytes)
-	return true
+//Helper: Append reading to the vehicle's history - one composite key vehicle~seq per accepted reading, returns seq
+func (rdg *ReadingAsset) appendReadingHistory(stub shim.ChaincodeStubInterface, reading Reading) (int, error) {
+	seq, err := nextSequence(stub, readingHistoryKeyType, reading.VehicleID)
+	if err != nil {
+		return 0, err
+	}
+	historyKey, err := createSequenceKey(stub, readingHistoryKeyType, reading.VehicleID, seq)
+	if err != nil {
+		return 0, errors.New("appendReadingHistory: Error creating history key for vehicle with ID: " + reading.VehicleID)
+	}
+	entry := ReadingHistoryEntry{
+		ObjectType: "Asset.ReadingHistory",
+		VehicleID:  reading.VehicleID,
+		Sequence:   seq,
+		Reading:    reading,
+	}
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		return 0, errors.New("appendReadingHistory: Error converting reading history record JSON")
+	}
+	err = stub.PutState(historyKey, bytes)
+	if err != nil {
+		return 0, errors.New("appendReadingHistory: Error storing reading history record")
+	}
+	return seq, nil
+}
+
+//Helper: Retrieve reading history entry - the accepted Reading with sequence number seq of a vehicle
+func (rdg *ReadingAsset) retrieveReadingHistoryEntry(stub shim.ChaincodeStubInterface, vehicleID string, seq int) (Reading, error) {
+	var entry ReadingHistoryEntry
+	bytes, err := retrieveRecordValue(stub, readingHistoryKeyType, vehicleID, seq)
+	if err != nil {
+		return entry.Reading, err
+	}
+	err = json.Unmarshal(bytes, &entry)
+	if err != nil {
+		return entry.Reading, errors.New("retrieveReadingHistoryEntry: Corrupt reading history record " + string(bytes))
+	}
+	return entry.Reading, nil
+}
+
+//Helper: Next sequence - increments the number of records of keyType of a vehicle, kept under the composite key
+//sequence~type~vehicle so that appending a record does not read the existing ones, and returns it
+func nextSequence(stub shim.ChaincodeStubInterface, keyType string, vehicleID string) (int, error) {
+	seq, err := lastSequence(stub, keyType, vehicleID)
+	if err != nil {
+		return 0, err
+	}
+	seq++
+	sequenceKey, err := stub.CreateCompositeKey(sequenceKeyType, []string{keyType, vehicleID})
+	if err != nil {
+		return 0, errors.New("nextSequence: Error creating sequence key for vehicle with ID: " + vehicleID)
+	}
+	err = stub.PutState(sequenceKey, []byte(strconv.Itoa(seq)))
+	if err != nil {
+		return 0, errors.New("nextSequence: Error storing sequence record")
+	}
+	return seq, nil
+}
+
+//Helper: Last sequence - the number of records of keyType of a vehicle, which is the sequence number of the last one;
+//records stored before the sequence key existed are counted
+func lastSequence(stub shim.ChaincodeStubInterface, keyType string, vehicleID string) (int, error) {
+	sequenceKey, err := stub.CreateCompositeKey(sequenceKeyType, []string{keyType, vehicleID})
+	if err != nil {
+		return 0, errors.New("lastSequence: Error creating sequence key for vehicle with ID: " + vehicleID)
+	}
+	bytes, err := stub.GetState(sequenceKey)
+	if err != nil {
+		return 0, errors.New("lastSequence: Error retrieving sequence record for vehicle with ID: " + vehicleID)
+	}
+	if bytes == nil {
+		values, err := retrieveRecordValues(stub, keyType, vehicleID)
+		return len(values), err
+	}
+	seq, err := strconv.Atoi(string(bytes))
+	if err != nil {
+		return 0, errors.New("lastSequence: Corrupt sequence record " + string(bytes))
+	}
+	return seq, nil
+}
+
+//createSequenceKey - composite key of keyType of the record of a vehicle with sequence number seq
+func createSequenceKey(stub shim.ChaincodeStubInterface, keyType string, vehicleID string, seq int) (string, error) {
+	return stub.CreateCompositeKey(keyType, []string{vehicleID, formatSequence(seq)})
+}
+
+//formatSequence - sequence numbers are zero padded in composite keys so that a range scan over the records of a
+//vehicle returns them in order
+func formatSequence(seq int) string {
+	return fmt.Sprintf("%010d", seq)
+}
+
+//Helper: Retrieve the value stored under the composite key of keyType for a vehicle and sequence number seq
+func retrieveRecordValue(stub shim.ChaincodeStubInterface, keyType string, vehicleID string, seq int) ([]byte, error) {
+	recordKey, err := createSequenceKey(stub, keyType, vehicleID, seq)
+	if err != nil {
+		return nil, errors.New("retrieveRecordValue: Error creating " + keyType + " key for vehicle with ID: " + vehicleID)
+	}
+	bytes, err := stub.GetState(recordKey)
+	if err != nil || bytes == nil {
+		return nil, errors.New("retrieveRecordValue: Error retrieving " + keyType + " record " + strconv.Itoa(seq) +
+			" for vehicle with ID: " + vehicleID)
+	}
+	return bytes, nil
 }
 
-//deleteKeyFromArray
-func deleteKeyFromStringArray(array []string, key string) (newArray []string, err error) {
-	for _, entry := range array {
-		if entry != key {
-			newArray = append(newArray, entry)
+//Helper: Retrieve the values stored under the composite keys of keyType for a vehicle, in key order - the bytes as
+//stored, so a hash over them does not depend on how the records decode
+func retrieveRecordValues(stub shim.ChaincodeStubInterface, keyType string, vehicleID string) ([][]byte, error) {
+	values := [][]byte{}
+	iterator, err := stub.GetStateByPartialCompositeKey(keyType, []string{vehicleID})
+	if err != nil {
+		return values, errors.New("retrieveRecordValues: Error retrieving " + keyType + " records for vehicle with ID: " + vehicleID)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return values, errors.New("retrieveRecordValues: Error iterating " + keyType + " records for vehicle with ID: " + vehicleID)
 		}
+		values = append(values, kv.Value)
 	}
-	if len(newArray) == len(array) {
-		return newArray, errors.New("Specified Key: " + key + " not found in Array")
+	return values, nil
+}
+
+//Helper: Retrieve reading history - the accepted Readings of a vehicle, oldest first
+func (rdg *ReadingAsset) retrieveReadingHistory(stub shim.ChaincodeStubInterface, vehicleID string) ([]Reading, error) {
+	readings := []Reading{}
+	values, err := retrieveRecordValues(stub, readingHistoryKeyType, vehicleID)
+	if err != nil {
+		return readings, err
+	}
+	for _, value := range values {
+		var entry ReadingHistoryEntry
+		err = json.Unmarshal(value, &entry)
+		if err != nil {
+			return readings, errors.New("retrieveReadingHistory: Corrupt reading history record " + string(value))
+		}
+		readings = append(readings, entry.Reading)
 	}
-	return newArray, nil
+	return readings, nil
+}
+
+//Helper: Save purge record - who archived which readings, stored under the composite key purge~txID
+func (rdg *ReadingAsset) savePurgeRecord(stub shim.ChaincodeStubInterface, vehicleIDs []string) (PurgeRecord, error) {
+	purge := PurgeRecord{
+		ObjectType: "Event.ReadingsPurged",
+		TxID:       stub.GetTxID(),
+		VehicleIDs: vehicleIDs,
+	}
+	submitter, err := getSubmitter(stub)
+	if err != nil {
+		return purge, errors.New("savePurgeRecord: " + err.Error())
+	}
+	purge.PurgedBy = submitter
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return purge, errors.New("savePurgeRecord: " + err.Error())
+	}
+	purge.Timestamp = txTime.Format(time.RFC3339Nano)
+	purgeKey, err := stub.CreateCompositeKey(purgeKeyType, []string{purge.TxID})
+	if err != nil {
+		return purge, errors.New("savePurgeRecord: Error creating purge key")
+	}
+	bytes, err := json.Marshal(purge)
+	if err != nil {
+		return purge, errors.New("savePurgeRecord: Error converting purge record JSON")
+	}
+	err = stub.PutState(purgeKey, bytes)
+	if err != nil {
+		return purge, errors.New("savePurgeRecord: Error storing purge record")
+	}
+	return purge, nil
 }
 
 //Helper: Retrieve purchaser
@@ -296,10 +984,14 @@ func (rdg *ReadingAsset) retrieveReading(stub shim.ChaincodeStubInterface, readi
 	if err != nil {
 		return readingAsByteArray, errors.New("retrieveReading: Error retrieving reading with ID: " + readingID)
 	}
+	if bytes == nil {
+		return readingAsByteArray, newError(NOTFOUND, codeNotFound, "retrieveReading: No reading found with ID: "+readingID)
+	}
 	err = json.Unmarshal(bytes, &reading)
 	if err != nil {
 		return readingAsByteArray, errors.New("retrieveReading: Corrupt reading record " + string(bytes))
 	}
+	reading.Unit = normalizeUnit(reading.Unit)
 	readingAsByteArray, err = json.Marshal(reading)
 	if err != nil {
 		return readingAsByteArray, errors.New("readReading: Invalid reading Object - Not a  valid JSON")
@@ -307,19 +999,105 @@ func (rdg *ReadingAsset) retrieveReading(stub shim.ChaincodeStubInterface, readi
 	return readingAsByteArray, nil
 }
 
-//getReadingFromArgs - construct a reading structure from string array of arguments
-func getReadingFromArgs(args []string) (reading Reading, err error) {
+//stampReading - records the submitting identity and the transaction on the reading
+func stampReading(stub shim.ChaincodeStubInterface, reading *Reading) error {
+	submitter, err := getSubmitter(stub)
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	reading.SubmitterMSPID = submitter.MSPID
+	reading.SubmitterSubject = submitter.Subject
+	reading.TxID = stub.GetTxID()
+	reading.TxTimestamp = txTime.Format(time.RFC3339Nano)
+	return nil
+}
 
-	if strings.Contains(args[0], "\"vehicleID\"") == false ||
-		strings.Contains(args[0], "\"docType\"") == false ||
-		strings.Contains(args[0], "\"reading\"") == false ||
-		strings.Contains(args[0], "\"creationDate\"") == false {
-		return reading, errors.New("Unknown field: Input JSON does not comply to schema")
+//getTxTime - transaction timestamp of the proposal in UTC; it is set by the submitting client and not validated by the
+//peers, so it is only recorded with the changes, never trusted as the time of the ledger
+func getTxTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, errors.New("Error getting transaction timestamp")
+	}
+	txTime, err := ptypes.Timestamp(txTimestamp)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid transaction timestamp")
 	}
+	return txTime, nil
+}
+
+//readingSchema - schema of the Reading JSON accepted by addNewReading and updateReading
+var readingSchema = []fieldSchema{
+	{name: "vehicleID", required: true, validate: stringField(checkID)},
+	{name: "docType", required: true, validate: stringField(checkEnum("Asset.Reading"))},
+	{name: "reading", required: true, validate: numberField(checkReadingValue)},
+	{name: "unit", required: true, validate: stringField(checkEnum(unitKilometres, unitMiles))},
+	{name: "creationDate", required: true, validate: stringField(checkTimestamp)},
+	{name: "geohash", validate: readOnlyField},
+	{name: "source", validate: stringField(checkEnum(sources...))},
+	{name: "sourceRef", validate: stringField(checkID)},
+	{name: "confidence", validate: readOnlyField},
+	{name: "signedBy", validate: readOnlyField},
+	{name: "signedCounter", validate: readOnlyField},
+	{name: "odometerOffset", validate: readOnlyField},
+	{name: "trueMileage", validate: readOnlyField},
+	{name: "flags", validate: readOnlyField},
+	{name: "locationHash", validate: readOnlyField},
+	{name: "submitterMSPID", validate: readOnlyField},
+	{name: "submitterSubject", validate: readOnlyField},
+	{name: "txID", validate: readOnlyField},
+	{name: "txTimestamp", validate: readOnlyField},
+}
 
+//Helper: Get the Reading of the arguments - a Reading JSON, or a SignedReading envelope verified with the key of its
+//device
+func (rdg *ReadingAsset) getReadingInput(stub shim.ChaincodeStubInterface, args []string) (Reading, error) {
+	if len(args) == 1 && isSignedReading(args[0]) {
+		return rdg.getSignedReading(stub, args[0])
+	}
+	reading, err := getReadingFromArgs(args)
+	if err != nil {
+		return reading, newValidationError("Reading Data is Corrupted", err)
+	}
+	return reading, nil
+}
+
+//getReadingFromArgs - construct a reading structure from string array of arguments
+func getReadingFromArgs(args []string) (reading Reading, err error) {
+	if len(args) != 1 {
+		return reading, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one Reading JSON argument"}}}
+	}
+	err = validateInput(args[0], readingSchema)
+	if err != nil {
+		return reading, err
+	}
 	err = json.Unmarshal([]byte(args[0]), &reading)
 	if err != nil {
 		return reading, err
 	}
+	reading.CreationDate, err = normalizeReadingTime(reading.CreationDate)
+	if err != nil {
+		return reading, err
+	}
 	return reading, nil
 }
+
+//getPaginationFromArgs - page size and bookmark from the optional arguments of a paginated query
+func getPaginationFromArgs(args []string) (pageSize int32, bookmark string, err error) {
+	pageSize = defaultPageSize
+	if len(args) > 0 && args[0] != "" {
+		size, err := strconv.ParseInt(args[0], 10, 32)
+		if err != nil || size < 1 || size > maxPageSize {
+			return pageSize, bookmark, fmt.Errorf("Page size must be a number between 1 and %d", maxPageSize)
+		}
+		pageSize = int32(size)
+	}
+	if len(args) > 1 {
+		bookmark = args[1]
+	}
+	return pageSize, bookmark, nil
+}
@@ -6,45 +6,81 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
 )
 
 //TestReadingAsset_Init
 func TestReadingAsset_Init(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
-	checkState(t, stub, "readingIDIndex", getExpectedReadingIDIndex("init"))
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkReadingIDIndex(t, stub, []string{})
+}
+
+//TestReadingAsset_Init_migrateReadingIDIndex
+func TestReadingAsset_Init_migrateReadingIDIndex(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	stub.MockTransactionStart("0")
+	stub.PutState("readingIDIndex", getLegacyReadingIDIndex())
+	stub.PutState("100001", getNewReadingExpected())
+	stub.MockTransactionEnd("0")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	if stub.State["readingIDIndex"] != nil {
+		fmt.Println("Legacy readingIDIndex array was expected to be removed")
+		t.FailNow()
+	}
+	checkReadingIDIndex(t, stub, []string{"100001"})
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReadingHistory"), []byte("100001")})
+	if res.Status != shim.OK || string(res.Payload) != "["+string(getNewReadingExpected())+"]" {
+		fmt.Println("func readReadingHistory expected the migrated reading, Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkInvoke(t, stub, getSecondReadingAssetForTesting())
+	checkReadAllReadingsOK(t, stub)
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	checkReadReadingHistoryOK(t, stub, "100001")
 }
 
 //TestReadingAsset_InvokeUnknownFunction
 func TestReadingAsset_InvokeUnknownFunction(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvokeUnknownFunction(t, stub, [][]byte{[]byte("myFunction"), []byte("docType:Asset")})
 }
 
 //TestReadingAsset_Invoke_addNewReading
 func TestReadingAsset_Invoke_addNewReadingOK(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	newReadingID := "100001"
 	checkState(t, stub, newReadingID, getNewReadingExpected())
-	checkState(t, stub, "readingIDIndex", getExpectedReadingIDIndex("addNewReading"))
+	checkReadingIDIndex(t, stub, []string{"100001"})
 }
 
 //TestReadingAsset_Invoke_addNewReadingUnknownField
 func TestReadingAsset_Invoke_addNewReadingUnknownField(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	res := stub.MockInvoke("1", getReadingAssetWithUnknownFieldForTesting())
 	if res.Status != shim.OK {
-		checkError(t, "Reading Data is Corrupted", res.Message)
+		checkError(t, "Reading Data is Corrupted: docType: is required; docuType: is not a known field", res.Message)
 	} else {
 		fmt.Println("Unknown Field Error was expected, but not raised")
 		t.FailNow()
@@ -54,12 +90,14 @@ func TestReadingAsset_Invoke_addNewReadingUnknownField(t *testing.T) {
 //TestReadingAsset_Invoke_addNewReading
 func TestReadingAsset_Invoke_addNewReadingDuplicate(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	newReadingID := "100001"
 	checkState(t, stub, newReadingID, getNewReadingExpected())
-	checkState(t, stub, "readingIDIndex", getExpectedReadingIDIndex("addNewReading"))
+	checkReadingIDIndex(t, stub, []string{"100001"})
 	res := stub.MockInvoke("1", getFirstReadingAssetForTesting())
 	if res.Status != shim.OK {
 		checkError(t, "This Reading already exists: 100001", res.Message)
@@ -69,11 +107,36 @@ func TestReadingAsset_Invoke_addNewReadingDuplicate(t *testing.T) {
 	}
 }
 
+//TestReadingAsset_Invoke_addNewReadingSubmitter
+func TestReadingAsset_Invoke_addNewReadingSubmitter(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org2MSP", "registry")
+	stub.TxTime = time.Date(2017, 12, 2, 8, 30, 0, 500, time.UTC)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("tx9", getFirstReadingAssetForTesting())
+	if res.Status != shim.OK {
+		fmt.Println("Invoke", "failed", string(res.Message))
+		t.FailNow()
+	}
+	var actual Reading
+	res = stub.MockInvoke("tx10", [][]byte{[]byte("readReading"), []byte("100001")})
+	err := json.Unmarshal(res.Payload, &actual)
+	if err != nil || actual.SubmitterMSPID != "Org2MSP" || actual.SubmitterSubject != "CN=registry@Org2MSP,O=Org2MSP" ||
+		actual.TxID != "tx9" || actual.TxTimestamp != "2017-12-02T08:30:00.0000005Z" {
+		fmt.Println("func readReading expected submitter and transaction of tx9, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+}
+
 //TestReadingAsset_Invoke_updateReadingOK  //change template
 func TestReadingAsset_Invoke_updateReadingOK(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	res := stub.MockInvoke("1", getUpdateReadingAssetForOKTesting())
 	if res.Status != shim.OK {
@@ -86,8 +149,10 @@ func TestReadingAsset_Invoke_updateReadingOK(t *testing.T) {
 //TestReadingAsset_Invoke_updateReadingValueNOK  //change template
 func TestReadingAsset_Invoke_updateReadingValueNOK(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	res := stub.MockInvoke("1", getUpdateReadingAssetForValueNOKTesting())
 	if res.Status != shim.OK {
@@ -102,8 +167,10 @@ func TestReadingAsset_Invoke_updateReadingValueNOK(t *testing.T) {
 //TestReadingAsset_Invoke_updateReadingDateNOK  //change template
 func TestReadingAsset_Invoke_updateReadingDateNOK(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	res := stub.MockInvoke("1", getUpdateReadingAssetForDateNOKTesting())
 	if res.Status != shim.OK {
@@ -118,36 +185,80 @@ func TestReadingAsset_Invoke_updateReadingDateNOK(t *testing.T) {
 //TestReadingAsset_Invoke_removeAllReadingsOK  //change template
 func TestReadingAsset_Invoke_removeAllReadingsOK(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInitWithPurgeConfirmation(t, stub)
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	checkInvoke(t, stub, getSecondReadingAssetForTesting())
 	checkReadAllReadingsOK(t, stub)
-	checkState(t, stub, "readingIDIndex", getExpectedReadingIDIndex("beforeRemoveReading"))
-	checkInvoke(t, stub, getRemoveAllReadingAssetsForTesting())
-	checkState(t, stub, "readingIDIndex", getExpectedReadingIDIndex(""))
+	checkReadingIDIndex(t, stub, []string{"100001", "100002"})
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkRemoveAllReadings(t, stub)
+	checkReadingIDIndex(t, stub, []string{})
+	checkPurgeEvent(t, stub, []string{"100001", "100002"})
 }
 
 //TestReadingAsset_Invoke_removeReadingNOK  //change template
 func TestReadingAsset_Invoke_removeAllReadingsNOK(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
-	res := stub.MockInvoke("1", getRemoveAllReadingAssetsForTesting())
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkInitWithPurgeConfirmation(t, stub)
+	res := removeAllReadingsForTesting(stub, "1")
 	if res.Status != shim.OK {
 		checkError(t, "removeAllReadings: No readings to remove", res.Message)
 	} else {
 		fmt.Println("Error was expected, but not raised")
 		t.FailNow()
 	}
-	checkState(t, stub, "readingIDIndex", getExpectedReadingIDIndex(""))
+	checkReadingIDIndex(t, stub, []string{})
+}
+
+//TestReadingAsset_Invoke_removeAllReadingsConfirmation
+func TestReadingAsset_Invoke_removeAllReadingsConfirmation(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkUnauthorized(t, stub, getRemoveAllReadingAssetsForTesting(),
+		"removeAllReadings: Role workshop is not authorized - requires one of: admin")
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	res := removeAllReadingsForTesting(stub, "1")
+	if res.Status != shim.OK {
+		checkError(t, "removeAllReadings: No confirmation token configured - Init sets a new one after each purge", res.Message)
+	} else {
+		fmt.Println("Error was expected, but not raised")
+		t.FailNow()
+	}
+	checkInitWithPurgeConfirmation(t, stub)
+	res = invokeWithTransientForTesting(stub, "1", transientPurgeConfirmation, "wrong-token-0123456789", getRemoveAllReadingAssetsForTesting())
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeUnauthorized, "removeAllReadings: Confirmation token does not match")
+	checkUnauthorized(t, stub, getRemoveAllReadingAssetsForTesting(), "removeAllReadings: Confirmation token does not match")
+	res = stub.MockInvoke("1", [][]byte{[]byte("removeAllReadings"), []byte(purgeConfirmationForTesting)})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"removeAllReadings: Expects no arguments - the confirmation token is sent in the transient field purgeConfirmation")
+	checkState(t, stub, "100001", getNewReadingExpected())
+	checkRemoveAllReadings(t, stub)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "100001", 100, "2017-12-20T14:30:00+01:00", ""))
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	res = removeAllReadingsForTesting(stub, "1")
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeNotConfigured,
+		"removeAllReadings: No confirmation token configured - Init sets a new one after each purge")
+	checkInitWithPurgeConfirmation(t, stub)
+	checkRemoveAllReadings(t, stub)
 }
 
 //TestReadingAsset_Query_readReading
 func TestReadingAsset_Query_readReading(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	readingID := "100001"
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	checkReadReadingOK(t, stub, readingID)
@@ -157,13 +268,188 @@ func TestReadingAsset_Query_readReading(t *testing.T) {
 //TestReadingAsset_Query_readAllReadings
 func TestReadingAsset_Query_readAllReadings(t *testing.T) {
 	reading := new(ReadingAsset)
-	stub := shim.NewMockStub("reading", reading)
-	checkInit(t, stub, [][]byte{[]byte("init")})
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
 	checkInvoke(t, stub, getFirstReadingAssetForTesting())
 	checkInvoke(t, stub, getSecondReadingAssetForTesting())
 	checkReadAllReadingsOK(t, stub)
 }
 
+//TestReadingAsset_Query_readReadingHistory
+func TestReadingAsset_Query_readReadingHistory(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	res := stub.MockInvoke("1", getUpdateReadingAssetForValueNOKTesting())
+	if res.Status == shim.OK {
+		fmt.Println("Error was expected, but not raised")
+		t.FailNow()
+	}
+	checkReadReadingHistoryOK(t, stub, "100001")
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReadingHistory"), []byte("100002")})
+	if res.Status != shim.OK {
+		checkError(t, "readReadingHistory: No readings found for vehicle with ID: 100002", res.Message)
+	} else {
+		fmt.Println("Error was expected, but not raised")
+		t.FailNow()
+	}
+}
+
+//TestReadingAsset_Invoke_removeAllReadingsHistory
+func TestReadingAsset_Invoke_removeAllReadingsHistory(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInitWithPurgeConfirmation(t, stub)
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkRemoveAllReadings(t, stub)
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte("100001")})
+	if res.Status == shim.OK {
+		fmt.Println("Reading was expected to be archived, but is still present:", string(res.Payload))
+		t.FailNow()
+	}
+	checkReadReadingHistoryOK(t, stub, "100001")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	res = stub.MockInvoke("1", getFirstReadingAssetForTesting())
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeRollbackDetected,
+		"addNewReading: New Reading is less than Current Reading - cannot update")
+	res = stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100001", 150, "2017-12-10T10:15:00+01:00", ""))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeDateRegression,
+		"addNewReading: New Date is earlier than Current Date - cannot update")
+	checkReadReadingHistoryOK(t, stub, "100001")
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "100001", 150, "2017-12-21T10:15:00+01:00", ""))
+}
+
+//TestReadingAsset_Invoke_historySequence
+func TestReadingAsset_Invoke_historySequence(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	sequenceKey, _ := stub.CreateCompositeKey(sequenceKeyType, []string{readingHistoryKeyType, "100001"})
+	checkState(t, stub, sequenceKey, []byte("1"))
+	delete(stub.State, sequenceKey)
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	checkState(t, stub, sequenceKey, []byte("2"))
+	checkReadReadingHistoryOK(t, stub, "100001")
+}
+
+//TestReadingAsset_Query_getReadingAudit
+func TestReadingAsset_Query_getReadingAudit(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInitWithPurgeConfirmation(t, stub)
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("tx1", getFirstReadingAssetForTesting())
+	if res.Status != shim.OK {
+		fmt.Println("Invoke", "failed", string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("tx2", getUpdateReadingAssetForOKTesting())
+	if res.Status != shim.OK {
+		fmt.Println("Invoke", "failed", string(res.Message))
+		t.FailNow()
+	}
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	res = removeAllReadingsForTesting(stub, "tx3")
+	if res.Status != shim.OK {
+		fmt.Println("Invoke", "failed", string(res.Message))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("tx4", [][]byte{[]byte("getReadingAudit"), []byte("100001")})
+	if res.Status != shim.OK {
+		fmt.Println("func getReadingAudit failed", string(res.Message))
+		t.FailNow()
+	}
+	var entries []ReadingAuditEntry
+	err := json.Unmarshal(res.Payload, &entries)
+	if err != nil || len(entries) != 3 {
+		fmt.Println("func getReadingAudit expected 3 versions, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	if entries[0].TxID != "tx3" || !entries[0].IsDelete || entries[0].Reading != nil {
+		fmt.Println("func getReadingAudit expected deletion by tx3, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	if entries[1].TxID != "tx2" || entries[1].IsDelete || entries[1].Reading.Reading != 100 || entries[1].Timestamp == "" {
+		fmt.Println("func getReadingAudit expected update by tx2, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	if entries[2].TxID != "tx1" || entries[2].Reading.Reading != 50 {
+		fmt.Println("func getReadingAudit expected creation by tx1, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("tx5", [][]byte{[]byte("getReadingAudit"), []byte("100002")})
+	if res.Status != shim.OK {
+		checkError(t, "getReadingAudit: No history found for reading with ID: 100002", res.Message)
+	} else {
+		fmt.Println("Error was expected, but not raised")
+		t.FailNow()
+	}
+}
+
+//TestReadingAsset_Query_readAllReadingsPaginated
+func TestReadingAsset_Query_readAllReadingsPaginated(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkInvoke(t, stub, getSecondReadingAssetForTesting())
+	firstPage := checkReadAllReadingsPage(t, stub, "1", "", "100001")
+	if firstPage.Bookmark == "" {
+		fmt.Println("func readAllReadings expected a bookmark for the next page")
+		t.FailNow()
+	}
+	secondPage := checkReadAllReadingsPage(t, stub, "1", firstPage.Bookmark, "100002")
+	if secondPage.Bookmark != "" {
+		fmt.Println("func readAllReadings expected no bookmark on the last page, Actual:", secondPage.Bookmark)
+		t.FailNow()
+	}
+	res := stub.MockInvoke("1", [][]byte{[]byte("readAllReadings"), []byte("0")})
+	if res.Status != shim.OK {
+		checkError(t, "readAllReadings: Page size must be a number between 1 and 1000", res.Message)
+	} else {
+		fmt.Println("Error was expected, but not raised")
+		t.FailNow()
+	}
+}
+
+//TestReadingAsset_Query_queryReadings
+func TestReadingAsset_Query_queryReadings(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkInvoke(t, stub, getSecondReadingAssetForTesting())
+	checkInvoke(t, stub, getUpdateReadingAssetForOKTesting())
+	checkQueryReadingsOK(t, stub, "{\"vehicleID\":\"100001\"}", []string{"100001"})
+	checkQueryReadingsOK(t, stub, "{\"creationDate\":{\"$gt\":\"2017-12-10T00:00:00.000Z\"}}", []string{"100001"})
+	checkQueryReadingsOK(t, stub, "{\"docType\":\"Asset.ReadingHistory\"}", []string{"100001", "100002"})
+	res := stub.MockInvoke("1", [][]byte{[]byte("queryReadings"), []byte("[\"reading\"]")})
+	if res.Status != shim.OK {
+		checkError(t, "queryReadings: Selector is not a valid JSON object", res.Message)
+	} else {
+		fmt.Println("Error was expected, but not raised")
+		t.FailNow()
+	}
+}
+
 /*
 *
 *	Helper Functions
@@ -172,51 +458,74 @@ func TestReadingAsset_Query_readAllReadings(t *testing.T) {
 //Get first ReadingAsset for testing
 func getFirstReadingAssetForTesting() [][]byte {
 	return [][]byte{[]byte("addNewReading"),
-		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":\"50\",\"creationDate\":\"12/01/2017\"}")}
+		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":50,\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00+01:00\"}")}
 }
 
 //Get ReadingAsset with unknown field for testing
 func getReadingAssetWithUnknownFieldForTesting() [][]byte {
 	return [][]byte{[]byte("addNewReading"),
-		[]byte("{\"vehicleID\":\"100001\",\"docuType\":\"Asset.Reading\",\"reading\":\"50\",\"creationDate\":\"12/01/2017\"}")}
+		[]byte("{\"vehicleID\":\"100001\",\"docuType\":\"Asset.Reading\",\"reading\":50,\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00+01:00\"}")}
 }
 
 //Get second ReadingAsset for testing
 func getSecondReadingAssetForTesting() [][]byte {
 	return [][]byte{[]byte("addNewReading"),
-		[]byte("{\"vehicleID\":\"100002\",\"docType\":\"Asset.Reading\",\"reading\":\"70\",\"creationDate\":\"12/01/2017\"}")}
+		[]byte("{\"vehicleID\":\"100002\",\"docType\":\"Asset.Reading\",\"reading\":70,\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00+01:00\"}")}
 }
 
 //Get update ReadingAsset for OK testing
 func getUpdateReadingAssetForOKTesting() [][]byte {
 	return [][]byte{[]byte("updateReading"),
-		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":\"100\",\"creationDate\":\"12/20/2017\"}")}
+		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":100,\"unit\":\"km\",\"creationDate\":\"2017-12-20T08:30:00-05:00\"}")}
 }
 
 //Get update ReadingAsset for reading value NOK testing
 func getUpdateReadingAssetForValueNOKTesting() [][]byte {
 	return [][]byte{[]byte("updateReading"),
-		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":\"20\",\"creationDate\":\"12/01/2017\"}")}
+		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":20,\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00+01:00\"}")}
 }
 
 //Get update ReadingAsset for reading Date NOK testing
 func getUpdateReadingAssetForDateNOKTesting() [][]byte {
 	return [][]byte{[]byte("updateReading"),
-		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":\"100\",\"creationDate\":\"11/20/2017\"}")}
+		[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Reading\",\"reading\":100,\"unit\":\"km\",\"creationDate\":\"2017-11-20T12:00:00Z\"}")}
 }
 
-//Get remove all ReadingAssets for testing //change template
+//purgeConfirmationForTesting - confirmation token of removeAllReadings for testing
+const purgeConfirmationForTesting = "confirm-purge-0123456789"
+
+//Get remove all ReadingAssets for testing, without the confirmation token //change template
 func getRemoveAllReadingAssetsForTesting() [][]byte {
 	return [][]byte{[]byte("removeAllReadings")}
 }
 
+//Invoke removeAllReadings with the confirmation token in the transient field purgeConfirmation for testing
+func removeAllReadingsForTesting(stub *ExtendedMockStub, txID string) peer.Response {
+	return invokeWithTransientForTesting(stub, txID, transientPurgeConfirmation, purgeConfirmationForTesting, getRemoveAllReadingAssetsForTesting())
+}
+
+//Init with args and the confirmation token of removeAllReadings in the transient field purgeConfirmation for testing
+func initWithPurgeConfirmationForTesting(stub *ExtendedMockStub, args [][]byte) peer.Response {
+	stub.Transient = map[string][]byte{transientPurgeConfirmation: []byte(purgeConfirmationForTesting)}
+	res := stub.MockInit("1", args)
+	stub.Transient = nil
+	return res
+}
+
+//Get Init arguments admitting writes of Org1MSP and Org2MSP, followed by the configuration fields, for testing
+func getInitForTesting(fields string) [][]byte {
+	return [][]byte{[]byte("init"), []byte("{\"writerMSPIDs\":[\"Org1MSP\",\"Org2MSP\"]" + fields + "}")}
+}
+
 //Get an expected value for testing
 func getNewReadingExpected() []byte {
 	var reading Reading
 	reading.VehicleID = "100001"
 	reading.ObjectType = "Asset.Reading"
-	reading.Reading = "50"
-	reading.CreationDate = "12/01/2017"
+	reading.Unit = "km"
+	reading.Reading = 50
+	reading.CreationDate = "2017-12-01T09:15:00.000Z"
+	stampReadingForTesting(&reading, "workshop")
 	readingJSON, err := json.Marshal(reading)
 	if err != nil {
 		fmt.Println("Error converting a Reading record to JSON")
@@ -230,8 +539,10 @@ func getUpdatedReadingExpected() []byte {
 	var reading Reading
 	reading.VehicleID = "100001"
 	reading.ObjectType = "Asset.Reading"
-	reading.Reading = "100"
-	reading.CreationDate = "12/20/2017"
+	reading.Unit = "km"
+	reading.Reading = 100
+	reading.CreationDate = "2017-12-20T13:30:00.000Z"
+	stampReadingForTesting(&reading, "workshop")
 	readingJSON, err := json.Marshal(reading)
 	if err != nil {
 		fmt.Println("Error converting a Reading record to JSON")
@@ -246,13 +557,39 @@ func getExpectedReadings() []byte {
 	var reading Reading
 	reading.VehicleID = "100001"
 	reading.ObjectType = "Asset.Reading"
-	reading.Reading = "50"
-	reading.CreationDate = "12/01/2017"
+	reading.Unit = "km"
+	reading.Reading = 50
+	reading.CreationDate = "2017-12-01T09:15:00.000Z"
+	stampReadingForTesting(&reading, "workshop")
 	readings = append(readings, reading)
 	reading.VehicleID = "100002"
 	reading.ObjectType = "Asset.Reading"
-	reading.Reading = "70"
-	reading.CreationDate = "12/01/2017"
+	reading.Unit = "km"
+	reading.Reading = 70
+	reading.CreationDate = "2017-12-01T09:15:00.000Z"
+	stampReadingForTesting(&reading, "workshop")
+	readings = append(readings, reading)
+	readingJSON, err := json.Marshal(ReadingPage{Records: readings, FetchedCount: 2, Bookmark: ""})
+	if err != nil {
+		fmt.Println("Error converting reading records to JSON")
+		return nil
+	}
+	return []byte(readingJSON)
+}
+
+//Get expected history of the first Reading after one update for testing
+func getExpectedReadingHistory() []byte {
+	var readings []Reading
+	var reading Reading
+	reading.VehicleID = "100001"
+	reading.ObjectType = "Asset.Reading"
+	reading.Unit = "km"
+	reading.Reading = 50
+	reading.CreationDate = "2017-12-01T09:15:00.000Z"
+	stampReadingForTesting(&reading, "workshop")
+	readings = append(readings, reading)
+	reading.Reading = 100
+	reading.CreationDate = "2017-12-20T13:30:00.000Z"
 	readings = append(readings, reading)
 	readingJSON, err := json.Marshal(readings)
 	if err != nil {
@@ -262,46 +599,30 @@ func getExpectedReadings() []byte {
 	return []byte(readingJSON)
 }
 
-func getExpectedReadingIDIndex(funcName string) []byte {
+//Set the submitter and transaction fields the chaincode records for a reading invoked as tx "1" by role of Org1MSP
+func stampReadingForTesting(reading *Reading, role string) {
+	reading.SubmitterMSPID = "Org1MSP"
+	reading.SubmitterSubject = "CN=" + role + "@Org1MSP,O=Org1MSP"
+	reading.TxID = "1"
+	reading.TxTimestamp = defaultTxTimeForTesting.Format(time.RFC3339Nano)
+	reading.Source = defaultSources[role]
+	reading.Confidence = sourceConfidence[reading.Source]
+}
+
+//Get a legacy readingIDIndex array holding the first Reading for testing
+func getLegacyReadingIDIndex() []byte {
 	var readingIDIndex ReadingIDIndex
-	switch funcName {
-	case "addNewReading":
-		readingIDIndex.VehicleIDs = append(readingIDIndex.VehicleIDs, "100001")
-		readingIDIndexBytes, err := json.Marshal(readingIDIndex)
-		if err != nil {
-			fmt.Println("Error converting ReadingIDIndex to JSON")
-			return nil
-		}
-		return readingIDIndexBytes
-	case "beforeRemoveReading":
-		readingIDIndex.VehicleIDs = append(readingIDIndex.VehicleIDs, "100001")
-		readingIDIndex.VehicleIDs = append(readingIDIndex.VehicleIDs, "100002")
-		readingIDIndexBytes, err := json.Marshal(readingIDIndex)
-		if err != nil {
-			fmt.Println("Error converting ReadingIDIndex to JSON")
-			return nil
-		}
-		return readingIDIndexBytes
-	case "afterRemoveReading":
-		readingIDIndex.VehicleIDs = append(readingIDIndex.VehicleIDs, "100001")
-		readingIDIndexBytes, err := json.Marshal(readingIDIndex)
-		if err != nil {
-			fmt.Println("Error converting ReadingIDIndex to JSON")
-			return nil
-		}
-		return readingIDIndexBytes
-	default:
-		readingIDIndexBytes, err := json.Marshal(readingIDIndex)
-		if err != nil {
-			fmt.Println("Error converting ReadingIDIndex to JSON")
-			return nil
-		}
-		return readingIDIndexBytes
+	readingIDIndex.VehicleIDs = append(readingIDIndex.VehicleIDs, "100001")
+	readingIDIndexBytes, err := json.Marshal(readingIDIndex)
+	if err != nil {
+		fmt.Println("Error converting ReadingIDIndex to JSON")
+		return nil
 	}
+	return readingIDIndexBytes
 }
 
 //checkInit - helper to check the Initialization of chaincode: ReadingAsset
-func checkInit(t *testing.T, stub *shim.MockStub, args [][]byte) {
+func checkInit(t *testing.T, stub *ExtendedMockStub, args [][]byte) {
 	res := stub.MockInit("1", args)
 	if res.Status != shim.OK {
 		fmt.Println("Init failed", string(res.Message))
@@ -309,8 +630,27 @@ func checkInit(t *testing.T, stub *shim.MockStub, args [][]byte) {
 	}
 }
 
+//checkInitWithPurgeConfirmation - helper to check the Initialization of chaincode with the confirmation token of
+//removeAllReadings
+func checkInitWithPurgeConfirmation(t *testing.T, stub *ExtendedMockStub) {
+	res := initWithPurgeConfirmationForTesting(stub, getInitForTesting(""))
+	if res.Status != shim.OK {
+		fmt.Println("Init failed", string(res.Message))
+		t.FailNow()
+	}
+}
+
+//checkRemoveAllReadings - helper to check removeAllReadings with the confirmation token succeeds
+func checkRemoveAllReadings(t *testing.T, stub *ExtendedMockStub) {
+	res := removeAllReadingsForTesting(stub, "1")
+	if res.Status != shim.OK {
+		fmt.Println("func removeAllReadings failed", string(res.Message))
+		t.FailNow()
+	}
+}
+
 //checkState - helper for checking the chaincode state for a given stateKey afgainst an expected value
-func checkState(t *testing.T, stub *shim.MockStub, stateKey string, expectedState []byte) {
+func checkState(t *testing.T, stub *ExtendedMockStub, stateKey string, expectedState []byte) {
 	actualState := stub.State[stateKey]
 	if actualState == nil {
 		fmt.Println("State for ", stateKey, ": failed to get value")
@@ -323,8 +663,23 @@ func checkState(t *testing.T, stub *shim.MockStub, stateKey string, expectedStat
 	}
 }
 
+//checkReadingIDIndex - helper for checking the reading IDs held by the readingIDIndex composite keys
+func checkReadingIDIndex(t *testing.T, stub *ExtendedMockStub, expectedIDs []string) {
+	stub.MockTransactionStart("checkReadingIDIndex")
+	defer stub.MockTransactionEnd("checkReadingIDIndex")
+	actualIDs, err := new(ReadingAsset).retrieveReadingIDs(stub)
+	if err != nil {
+		fmt.Println("Failed to get readingIDIndex:", err.Error())
+		t.FailNow()
+	}
+	if strings.Join(actualIDs, ",") != strings.Join(expectedIDs, ",") {
+		fmt.Println("Incorrect readingIDIndex: \nExpected: ", expectedIDs, "\nActual  : ", actualIDs)
+		t.FailNow()
+	}
+}
+
 //checkInvoke - helper for checking Invoke of chaincode
-func checkInvoke(t *testing.T, stub *shim.MockStub, args [][]byte) {
+func checkInvoke(t *testing.T, stub *ExtendedMockStub, args [][]byte) {
 	res := stub.MockInvoke("1", args)
 	if res.Status != shim.OK {
 		fmt.Println("Invoke", args, "failed", string(res.Message))
@@ -332,7 +687,7 @@ func checkInvoke(t *testing.T, stub *shim.MockStub, args [][]byte) {
 	}
 }
 
-func checkInvokeUnknownFunction(t *testing.T, stub *shim.MockStub, args [][]byte) {
+func checkInvokeUnknownFunction(t *testing.T, stub *ExtendedMockStub, args [][]byte) {
 	res := stub.MockInvoke("1", args)
 	if res.Status != shim.OK {
 		expectedErr := "Received unknown function invocation"
@@ -347,7 +702,7 @@ func checkInvokeUnknownFunction(t *testing.T, stub *shim.MockStub, args [][]byte
 }
 
 //checkReadReadingOK - helper for positive test readReading
-func checkReadReadingOK(t *testing.T, stub *shim.MockStub, readingID string) {
+func checkReadReadingOK(t *testing.T, stub *ExtendedMockStub, readingID string) {
 	res := stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte(readingID)})
 	if res.Status != shim.OK {
 		fmt.Println("func readReading with ID: ", readingID, " failed"+string(res.Message))
@@ -364,11 +719,11 @@ func checkReadReadingOK(t *testing.T, stub *shim.MockStub, readingID string) {
 }
 
 //checkReadReadingNOK - helper for negative testing of readReading
-func checkReadReadingNOK(t *testing.T, stub *shim.MockStub, readingID string) {
+func checkReadReadingNOK(t *testing.T, stub *ExtendedMockStub, readingID string) {
 	//with no readingID
 	res := stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte("")})
 	if res.Status != shim.OK {
-		expectedErr := "retrieveReading: Corrupt reading record "
+		expectedErr := "retrieveReading: No reading found with ID: "
 		actualErr := string(res.Message)
 		if !(strings.Contains(actualErr, expectedErr)) {
 			fmt.Println("func readReading negative test: ", "Expected Error:", expectedErr, "Actual Error", actualErr)
@@ -379,7 +734,7 @@ func checkReadReadingNOK(t *testing.T, stub *shim.MockStub, readingID string) {
 	}
 }
 
-func checkReadAllReadingsOK(t *testing.T, stub *shim.MockStub) {
+func checkReadAllReadingsOK(t *testing.T, stub *ExtendedMockStub) {
 	res := stub.MockInvoke("1", [][]byte{[]byte("readAllReadings")})
 	if res.Status != shim.OK {
 		fmt.Println("func readAllReadings failed", string(res.Message))
@@ -395,7 +750,82 @@ func checkReadAllReadingsOK(t *testing.T, stub *shim.MockStub) {
 	}
 }
 
+//checkReadReadingHistoryOK - helper for positive test readReadingHistory
+func checkReadReadingHistoryOK(t *testing.T, stub *ExtendedMockStub, vehicleID string) {
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReadingHistory"), []byte(vehicleID)})
+	if res.Status != shim.OK {
+		fmt.Println("func readReadingHistory with ID: ", vehicleID, " failed"+string(res.Message))
+		t.FailNow()
+	}
+	if bytes.Compare(getExpectedReadingHistory(), []byte(res.Payload)) != 0 {
+		fmt.Println("func readReadingHistory Expected:\n", string(getExpectedReadingHistory()), "\nActual:\n", string(res.Payload))
+		t.FailNow()
+	}
+}
+
+//checkReadAllReadingsPage - helper for reading one page of size pageSize holding exactly the reading expectedID
+func checkReadAllReadingsPage(t *testing.T, stub *ExtendedMockStub, pageSize string, bookmark string, expectedID string) ReadingPage {
+	var page ReadingPage
+	res := stub.MockInvoke("1", [][]byte{[]byte("readAllReadings"), []byte(pageSize), []byte(bookmark)})
+	if res.Status != shim.OK {
+		fmt.Println("func readAllReadings failed", string(res.Message))
+		t.FailNow()
+	}
+	err := json.Unmarshal(res.Payload, &page)
+	if err != nil || page.FetchedCount != 1 || len(page.Records) != 1 || page.Records[0].VehicleID != expectedID {
+		fmt.Println("func readAllReadings expected one reading with ID:", expectedID, "Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	return page
+}
+
+//checkQueryReadingsOK - helper for checking the reading IDs returned by queryReadings for a selector
+func checkQueryReadingsOK(t *testing.T, stub *ExtendedMockStub, selector string, expectedIDs []string) {
+	var page ReadingPage
+	res := stub.MockInvoke("1", [][]byte{[]byte("queryReadings"), []byte(selector)})
+	if res.Status != shim.OK {
+		fmt.Println("func queryReadings with selector:", selector, "failed", string(res.Message))
+		t.FailNow()
+	}
+	err := json.Unmarshal(res.Payload, &page)
+	if err != nil || int(page.FetchedCount) != len(expectedIDs) || len(page.Records) != len(expectedIDs) {
+		fmt.Println("func queryReadings with selector:", selector, "Expected IDs:", expectedIDs, "Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	for i, record := range page.Records {
+		if record.VehicleID != expectedIDs[i] || record.ObjectType != "Asset.Reading" {
+			fmt.Println("func queryReadings with selector:", selector, "Expected IDs:", expectedIDs, "Actual:", string(res.Payload))
+			t.FailNow()
+		}
+	}
+}
+
+//checkPurgeEvent - helper for checking the ReadingsPurged event of the last invoke
+func checkPurgeEvent(t *testing.T, stub *ExtendedMockStub, expectedIDs []string) {
+	var purge PurgeRecord
+	select {
+	case event := <-stub.ChaincodeEventsChannel:
+		err := json.Unmarshal(event.Payload, &purge)
+		if event.EventName != "ReadingsPurged" || err != nil {
+			fmt.Println("Expected ReadingsPurged event, Actual:", event.EventName, string(event.Payload))
+			t.FailNow()
+		}
+	default:
+		fmt.Println("Expected ReadingsPurged event, but none was set")
+		t.FailNow()
+	}
+	if strings.Join(purge.VehicleIDs, ",") != strings.Join(expectedIDs, ",") || purge.PurgedBy.MSPID != "Org1MSP" ||
+		purge.PurgedBy.Subject != "CN=admin@Org1MSP,O=Org1MSP" || purge.TxID != "1" {
+		fmt.Println("Incorrect ReadingsPurged event:", purge)
+		t.FailNow()
+	}
+}
+
 func checkError(t *testing.T, exp string, act string) {
+	var envelope ChaincodeError
+	if json.Unmarshal([]byte(act), &envelope) == nil && envelope.Code != "" {
+		act = envelope.Message
+	}
 	if strings.Compare(exp, act) != 0 {
 		fmt.Println("Unexpected Error! Expecting ", exp, "\n Actual :", act)
 		t.FailNow()
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//readingTimeLayout - RFC 3339 in UTC with fixed millisecond precision, so stored timestamps sort lexically in CouchDB
+const readingTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+//legacyReadingDateLayout - US date layout of records stored before RFC 3339 timestamps were introduced
+const legacyReadingDateLayout = "01/02/2006"
+
+//normalizeReadingTime - parses an RFC 3339 timestamp with time zone offset and formats it in UTC
+func normalizeReadingTime(value string) (string, error) {
+	readingTime, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return "", errors.New("Timestamp " + value + " is not RFC 3339 with time zone offset, e.g. 2017-12-01T10:15:00+01:00")
+	}
+	return formatReadingTime(readingTime), nil
+}
+
+//formatReadingTime - formats an instant in the stored layout
+func formatReadingTime(readingTime time.Time) string {
+	return readingTime.UTC().Format(readingTimeLayout)
+}
+
+//parseReadingTime - compatibility parser for stored timestamps: RFC 3339, or the legacy US date layout at midnight UTC
+func parseReadingTime(value string) (time.Time, error) {
+	readingTime, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return readingTime.UTC(), nil
+	}
+	readingTime, err = time.Parse(legacyReadingDateLayout, value)
+	if err == nil {
+		return readingTime, nil
+	}
+	return time.Time{}, errors.New("Timestamp " + value + " is neither RFC 3339 nor " + legacyReadingDateLayout)
+}
+
+//Helper: Check the claimed reading time against the transaction time recorded as txTimestamp: it may lie at most
+//maxFutureSkew after and maxBackdate before it. The transaction time is set by the submitting client, so the window
+//only bounds the reading time a client claims relative to its own clock - it does not prove when the reading was taken.
+func (rdg *ReadingAsset) checkReadingTime(stub shim.ChaincodeStubInterface, creationDate string) error {
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil {
+		return err
+	}
+	maxFutureSkew, maxBackdate, err := config.readingTimeWindow()
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	readingTime, err := parseReadingTime(creationDate)
+	if err != nil {
+		return err
+	}
+	if readingTime.After(txTime.Add(maxFutureSkew)) {
+		return newError(BADREQUEST, codeTimestampRejected, "Reading time "+creationDate+" is more than "+
+			maxFutureSkew.String()+" after the transaction time "+formatReadingTime(txTime))
+	}
+	if readingTime.Before(txTime.Add(-maxBackdate)) {
+		return newError(BADREQUEST, codeTimestampRejected, "Reading time "+creationDate+" is more than "+
+			maxBackdate.String()+" before the transaction time "+formatReadingTime(txTime))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestReadingTime_normalizeReadingTime
+func TestReadingTime_normalizeReadingTime(t *testing.T) {
+	tests := map[string]string{
+		"2017-12-01T10:15:00+01:00":     "2017-12-01T09:15:00.000Z",
+		"2017-12-20T08:30:00-05:00":     "2017-12-20T13:30:00.000Z",
+		"2017-12-20T23:59:59.1234Z":     "2017-12-20T23:59:59.123Z",
+		"2017-12-31T23:30:00.500-02:00": "2018-01-01T01:30:00.500Z",
+	}
+	for input, expected := range tests {
+		actual, err := normalizeReadingTime(input)
+		if err != nil || actual != expected {
+			fmt.Println("normalizeReadingTime of", input, "Expected:", expected, "Actual:", actual, err)
+			t.FailNow()
+		}
+	}
+	for _, input := range []string{"12/01/2017", "20/12/2017", "2017-12-01", "2017-12-01T10:15:00"} {
+		_, err := normalizeReadingTime(input)
+		if err == nil {
+			fmt.Println("normalizeReadingTime of", input, "was expected to fail")
+			t.FailNow()
+		}
+	}
+}
+
+//TestReadingTime_parseReadingTime
+func TestReadingTime_parseReadingTime(t *testing.T) {
+	tests := map[string]time.Time{
+		"2017-12-01T09:15:00.000Z":  time.Date(2017, 12, 1, 9, 15, 0, 0, time.UTC),
+		"2017-12-01T10:15:00+01:00": time.Date(2017, 12, 1, 9, 15, 0, 0, time.UTC),
+		"12/01/2017":                time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for input, expected := range tests {
+		actual, err := parseReadingTime(input)
+		if err != nil || !actual.Equal(expected) {
+			fmt.Println("parseReadingTime of", input, "Expected:", expected, "Actual:", actual, err)
+			t.FailNow()
+		}
+	}
+	_, err := parseReadingTime("20/12/2017")
+	if err == nil {
+		fmt.Println("parseReadingTime of 20/12/2017 was expected to fail")
+		t.FailNow()
+	}
+}
+
+//TestReadingTime_updateReadingSameDay
+func TestReadingTime_updateReadingSameDay(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-12-01T14:00:00+01:00"))
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":60,\"unit\":\"km\"", "2017-12-01T12:59:00Z"))
+	if res.Status == shim.OK {
+		fmt.Println("updateReading earlier on the same day was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "updateReading: New Date is earlier than Current Date - cannot update", res.Message)
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":60,\"unit\":\"km\"", "2017-12-01T13:01:00Z"))
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "12/01/2017"))
+	if res.Status == shim.OK {
+		fmt.Println("addNewReading with legacy date layout was expected to fail")
+		t.FailNow()
+	}
+}
+
+//TestReadingTime_futureDatedReading
+func TestReadingTime_futureDatedReading(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-12-21T10:20:00+01:00"))
+	if res.Status == shim.OK {
+		fmt.Println("addNewReading dated after the transaction time was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "addNewReading: Reading time 2017-12-21T09:20:00.000Z is more than 15m0s after the transaction time "+
+		"2017-12-21T09:00:00.000Z", res.Message)
+	checkInvoke(t, stub, getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-12-21T09:10:00Z"))
+	stub.TxTime = time.Date(2017, 12, 21, 9, 30, 0, 0, time.UTC)
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":60,\"unit\":\"km\"", "2018-12-21T09:20:00Z"))
+	if res.Status == shim.OK {
+		fmt.Println("updateReading dated after the transaction time was expected to fail")
+		t.FailNow()
+	}
+}
+
+//TestReadingTime_backdatedReading
+func TestReadingTime_backdatedReading(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(",\"maxFutureSkew\":\"0s\",\"maxBackdate\":\"24h\""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-12-20T08:59:00Z"))
+	if res.Status == shim.OK {
+		fmt.Println("addNewReading dated before the backdate window was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "addNewReading: Reading time 2017-12-20T08:59:00.000Z is more than 24h0m0s before the transaction time "+
+		"2017-12-21T09:00:00.000Z", res.Message)
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-12-21T09:00:01Z"))
+	if res.Status == shim.OK {
+		fmt.Println("addNewReading dated after the transaction time was expected to fail")
+		t.FailNow()
+	}
+	checkInvoke(t, stub, getReadingForOdometerTesting("addNewReading", "\"reading\":50,\"unit\":\"km\"", "2017-12-21T09:00:00Z"))
+}
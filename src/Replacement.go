@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//replacementKeyType - object type of the composite keys replacement~vehicle~seq of the odometer replacements
+const replacementKeyType = "replacement~vehicle~seq"
+
+//OdometerReplacement - Swap of the odometer (instrument cluster) of a vehicle, stored under the composite key
+//replacement~vehicle~seq. Offset is the accumulated offset in km carried over to the readings of the new odometer.
+type OdometerReplacement struct {
+	ObjectType       string        `json:"docType"`
+	VehicleID        string        `json:"vehicleID"`
+	Sequence         int           `json:"seq"`
+	OldFinalReading  OdometerValue `json:"oldFinalReading"`
+	NewStartReading  OdometerValue `json:"newStartReading"`
+	Unit             string        `json:"unit"`
+	ReplacementDate  string        `json:"replacementDate"`
+	Evidence         string        `json:"evidence"`
+	Offset           OdometerValue `json:"offset"`
+	SubmitterMSPID   string        `json:"submitterMSPID"`
+	SubmitterSubject string        `json:"submitterSubject"`
+	TxID             string        `json:"txID"`
+	TxTimestamp      string        `json:"txTimestamp"`
+}
+
+//replacementSchema - schema of the OdometerReplacement JSON accepted by recordOdometerReplacement
+var replacementSchema = []fieldSchema{
+	{name: "vehicleID", required: true, validate: stringField(checkID)},
+	{name: "oldFinalReading", required: true, validate: numberField(checkReadingValue)},
+	{name: "newStartReading", required: true, validate: numberField(checkReadingValue)},
+	{name: "unit", required: true, validate: stringField(checkEnum(unitKilometres, unitMiles))},
+	{name: "replacementDate", required: true, validate: stringField(checkTimestamp)},
+	{name: "evidence", required: true, validate: stringField(checkText(1024))},
+}
+
+//Invoke Route: recordOdometerReplacement - the final reading of the old odometer must not roll back the current
+//Reading; the starting reading of the new odometer becomes the current Reading, carrying the accumulated offset
+func (rdg *ReadingAsset) recordOdometerReplacement(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	var currReading Reading
+	err := rdg.checkAccess(stub, replacementRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("recordOdometerReplacement: ", err))
+	}
+	replacement, err := getReplacementFromArgs(args)
+	if err != nil {
+		return validationFailed("recordOdometerReplacement: Replacement Data is Corrupted", err)
+	}
+	err = rdg.checkReadingTime(stub, replacement.ReplacementDate)
+	if err != nil {
+		return errorResponse(withPrefix("recordOdometerReplacement: ", err))
+	}
+	readingAsByteArray, err := rdg.retrieveReading(stub, replacement.VehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = json.Unmarshal(readingAsByteArray, &currReading)
+	if err != nil {
+		return internalError("recordOdometerReplacement: Error unmarshalling reading JSON")
+	}
+	oldFinalReading := Reading{Reading: replacement.OldFinalReading, Unit: replacement.Unit, OdometerOffset: currReading.OdometerOffset}
+	if isMileageRollback(currReading, oldFinalReading) {
+		return conflict(codeRollbackDetected, "recordOdometerReplacement: Final reading of the old odometer is less than Current Reading")
+	}
+	currDate, err := parseReadingTime(currReading.CreationDate)
+	if err != nil {
+		return errorResponse(withPrefix("recordOdometerReplacement: ", err))
+	}
+	replacementDate, err := parseReadingTime(replacement.ReplacementDate)
+	if err != nil {
+		return errorResponse(withPrefix("recordOdometerReplacement: ", err))
+	}
+	if currDate.After(replacementDate) {
+		return conflict(codeDateRegression, "recordOdometerReplacement: Replacement Date is earlier than Current Date")
+	}
+	replacement.Offset = currReading.OdometerOffset + OdometerValue(toKilometres(replacement.OldFinalReading, replacement.Unit)-
+		toKilometres(replacement.NewStartReading, replacement.Unit))
+	newReading := Reading{
+		VehicleID:      replacement.VehicleID,
+		ObjectType:     "Asset.Reading",
+		Reading:        replacement.NewStartReading,
+		Unit:           replacement.Unit,
+		CreationDate:   replacement.ReplacementDate,
+		OdometerOffset: replacement.Offset,
+		Source:         sourceWorkshop,
+		Confidence:     sourceConfidence[sourceWorkshop],
+	}
+	setTrueMileage(&newReading)
+	err = stampReading(stub, &newReading)
+	if err != nil {
+		return errorResponse(withPrefix("recordOdometerReplacement: ", err))
+	}
+	replacement.SubmitterMSPID = newReading.SubmitterMSPID
+	replacement.SubmitterSubject = newReading.SubmitterSubject
+	replacement.TxID = newReading.TxID
+	replacement.TxTimestamp = newReading.TxTimestamp
+	_, err = rdg.saveReading(stub, newReading)
+	if err != nil {
+		return errorResponse(err)
+	}
+	_, err = rdg.appendReadingHistory(stub, newReading)
+	if err != nil {
+		return errorResponse(err)
+	}
+	_, err = rdg.saveOdometerReplacement(stub, replacement)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = setReadingEvent(stub, eventOdometerReplaced, currReading, newReading, "Odometer replacement")
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+//Query Route: readOdometerReplacements - all odometer replacements of a vehicle, oldest first
+func (rdg *ReadingAsset) readOdometerReplacements(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	replacements, err := rdg.retrieveOdometerReplacements(stub, vehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if len(replacements) == 0 {
+		return notFound("readOdometerReplacements: No odometer replacements found for vehicle with ID: " + vehicleID)
+	}
+	bytes, err := json.Marshal(replacements)
+	if err != nil {
+		return internalError("readOdometerReplacements: Error marshalling odometer replacements JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//Helper: Save odometer replacement - one composite key replacement~vehicle~seq per replacement
+func (rdg *ReadingAsset) saveOdometerReplacement(stub shim.ChaincodeStubInterface, replacement OdometerReplacement) (bool, error) {
+	var err error
+	replacement.ObjectType = "Asset.OdometerReplacement"
+	replacement.Sequence, err = nextSequence(stub, replacementKeyType, replacement.VehicleID)
+	if err != nil {
+		return false, err
+	}
+	replacementKey, err := createSequenceKey(stub, replacementKeyType, replacement.VehicleID, replacement.Sequence)
+	if err != nil {
+		return false, errors.New("saveOdometerReplacement: Error creating replacement key for vehicle with ID: " + replacement.VehicleID)
+	}
+	bytes, err := json.Marshal(replacement)
+	if err != nil {
+		return false, errors.New("saveOdometerReplacement: Error converting odometer replacement record JSON")
+	}
+	err = stub.PutState(replacementKey, bytes)
+	if err != nil {
+		return false, errors.New("saveOdometerReplacement: Error storing odometer replacement record")
+	}
+	return true, nil
+}
+
+//Helper: Retrieve odometer replacements - the odometer replacements of a vehicle, oldest first
+func (rdg *ReadingAsset) retrieveOdometerReplacements(stub shim.ChaincodeStubInterface, vehicleID string) ([]OdometerReplacement, error) {
+	replacements := []OdometerReplacement{}
+	values, err := retrieveRecordValues(stub, replacementKeyType, vehicleID)
+	if err != nil {
+		return replacements, err
+	}
+	for _, value := range values {
+		var replacement OdometerReplacement
+		err = json.Unmarshal(value, &replacement)
+		if err != nil {
+			return replacements, errors.New("retrieveOdometerReplacements: Corrupt odometer replacement record " + string(value))
+		}
+		replacements = append(replacements, replacement)
+	}
+	return replacements, nil
+}
+
+//getReplacementFromArgs - construct an odometer replacement structure from string array of arguments
+func getReplacementFromArgs(args []string) (replacement OdometerReplacement, err error) {
+	if len(args) != 1 {
+		return replacement, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one OdometerReplacement JSON argument"}}}
+	}
+	err = validateInput(args[0], replacementSchema)
+	if err != nil {
+		return replacement, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &replacement)
+	if err != nil {
+		return replacement, err
+	}
+	replacement.ReplacementDate, err = normalizeReadingTime(replacement.ReplacementDate)
+	if err != nil {
+		return replacement, err
+	}
+	return replacement, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+//TestReplacement_recordOdometerReplacement
+func TestReplacement_recordOdometerReplacement(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkUnauthorized(t, stub, getReplacementForTesting(1200, 5, "2017-12-15T10:00:00Z"),
+		"recordOdometerReplacement: Role registry is not authorized - requires one of: workshop")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	res := stub.MockInvoke("1", getReplacementForTesting(40, 5, "2017-12-15T10:00:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeRollbackDetected,
+		"recordOdometerReplacement: Final reading of the old odometer is less than Current Reading")
+	res = stub.MockInvoke("1", getReplacementForTesting(1200, 5, "2017-11-30T10:00:00Z"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeDateRegression,
+		"recordOdometerReplacement: Replacement Date is earlier than Current Date")
+	checkInvoke(t, stub, getReplacementForTesting(1200, 5, "2017-12-15T10:00:00Z"))
+	checkReadingEvent(t, stub, eventOdometerReplaced,
+		ReadingEvent{VehicleID: "100001", OldValue: 50, OldUnit: "km", NewValue: 5, NewUnit: "km", Reason: "Odometer replacement"})
+	expected := Reading{VehicleID: "100001", ObjectType: "Asset.Reading", Reading: 5, Unit: "km",
+		CreationDate: "2017-12-15T10:00:00.000Z", OdometerOffset: 1195, TrueMileage: 1200}
+	stampReadingForTesting(&expected, "workshop")
+	expectedBytes, _ := json.Marshal(expected)
+	checkState(t, stub, "100001", expectedBytes)
+
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":4,\"unit\":\"km\"", "2017-12-20T08:30:00-05:00"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeRollbackDetected,
+		"updateReading: New Reading is less than Current Reading - cannot update")
+	checkInvoke(t, stub, getReadingForOdometerTesting("updateReading", "\"reading\":10,\"unit\":\"km\"", "2017-12-20T08:30:00-05:00"))
+	expected = Reading{VehicleID: "100001", ObjectType: "Asset.Reading", Reading: 10, Unit: "km",
+		CreationDate: "2017-12-20T13:30:00.000Z", OdometerOffset: 1195, TrueMileage: 1205}
+	stampReadingForTesting(&expected, "workshop")
+	expectedBytes, _ = json.Marshal(expected)
+	checkState(t, stub, "100001", expectedBytes)
+}
+
+//TestReplacement_readOdometerReplacements
+func TestReplacement_readOdometerReplacements(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkInvoke(t, stub, getReplacementForTesting(1200, 5, "2017-12-15T10:00:00Z"))
+	checkInvoke(t, stub, getReplacementForTesting(2000, 0, "2017-12-20T10:00:00Z"))
+	res := stub.MockInvoke("1", [][]byte{[]byte("readOdometerReplacements"), []byte("100001")})
+	var replacements []OdometerReplacement
+	err := json.Unmarshal(res.Payload, &replacements)
+	if err != nil || len(replacements) != 2 || replacements[0].Sequence != 1 || replacements[0].Offset != 1195 ||
+		replacements[1].Sequence != 2 || replacements[1].Offset != 3195 || replacements[1].Evidence != "Invoice 4711" {
+		fmt.Println("func readOdometerReplacements failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("readOdometerReplacements"), []byte("100002")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound,
+		"readOdometerReplacements: No odometer replacements found for vehicle with ID: 100002")
+}
+
+//Get an odometer replacement of vehicle 100001 in km for testing
+func getReplacementForTesting(oldFinalReading int, newStartReading int, replacementDate string) [][]byte {
+	return [][]byte{[]byte("recordOdometerReplacement"),
+		[]byte(fmt.Sprintf("{\"vehicleID\":\"100001\",\"oldFinalReading\":%d,\"newStartReading\":%d,\"unit\":\"km\","+
+			"\"replacementDate\":\"%s\",\"evidence\":\"Invoice 4711\"}", oldFinalReading, newStartReading, replacementDate))}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Sources of a Reading
+const (
+	sourceManual     = "manual"
+	sourceWorkshop   = "workshop"
+	sourceInspection = "inspection"
+	sourceTelematics = "telematics"
+	sourceRegistry   = "registry"
+)
+
+//Confidence levels of the sources
+const (
+	confidenceLow    = "low"
+	confidenceMedium = "medium"
+	confidenceHigh   = "high"
+)
+
+//sources - all sources, in the order of the schema's enum
+var sources = []string{sourceManual, sourceWorkshop, sourceInspection, sourceTelematics, sourceRegistry}
+
+//sourceConfidence - confidence level of the readings of each source: entered by hand, transmitted by a device, or
+//read off by certified staff
+var sourceConfidence = map[string]string{
+	sourceManual:     confidenceLow,
+	sourceTelematics: confidenceMedium,
+	sourceWorkshop:   confidenceHigh,
+	sourceInspection: confidenceHigh,
+	sourceRegistry:   confidenceHigh,
+}
+
+//sourceRoles - roles allowed to submit readings of each source
+var sourceRoles = map[string][]string{
+	sourceManual:     {"workshop", "registry"},
+	sourceWorkshop:   {"workshop"},
+	sourceInspection: {"inspector"},
+	sourceTelematics: {"telematics", "workshop"},
+	sourceRegistry:   {"registry"},
+}
+
+//defaultSources - source of a reading submitted without one, by the role of the caller
+var defaultSources = map[string]string{
+	"workshop":   sourceWorkshop,
+	"inspector":  sourceInspection,
+	"telematics": sourceTelematics,
+	"registry":   sourceRegistry,
+}
+
+//sourceRefRequired - sources whose readings must name their origin in sourceRef: the test station or the device
+//serial number. Workshops and the registry are identified by the submitter, their sourceRef is optional.
+var sourceRefRequired = []string{sourceInspection, sourceTelematics}
+
+//sourceOverrideRules - sources of earlier readings that a new reading of the source may not override with a lower
+//mileage or an earlier date, however many readings of other sources were accepted since
+var sourceOverrideRules = map[string][]string{
+	sourceManual: {sourceInspection},
+}
+
+//Helper: Check the source of reading - defaults it by the caller's role, which must be allowed to submit readings of
+//the source, requires sourceRef where the source needs one and sets the confidence level. Signed telematics readings
+//are trusted like readings of certified staff; unsigned ones are rejected unless the configuration allows them.
+func (rdg *ReadingAsset) checkReadingSource(stub shim.ChaincodeStubInterface, reading *Reading) error {
+	role, found, err := cid.GetAttributeValue(stub, roleAttribute)
+	if err != nil || !found {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Caller has no "+roleAttribute+" attribute")
+	}
+	if reading.Source == "" {
+		reading.Source = defaultSources[role]
+	}
+	if !containsString(sourceRoles[reading.Source], role) {
+		return newError(UNAUTHORIZED, codeUnauthorized, "Role "+role+" may not submit "+reading.Source+
+			" readings - requires one of: "+strings.Join(sourceRoles[reading.Source], ", "))
+	}
+	if reading.SourceRef == "" && containsString(sourceRefRequired, reading.Source) {
+		return newValidationError("Reading Data is Corrupted",
+			ValidationError{Fields: []FieldError{{Field: "sourceRef", Message: "is required for " + reading.Source + " readings"}}})
+	}
+	reading.Confidence = sourceConfidence[reading.Source]
+	if reading.SignedBy != "" {
+		reading.Confidence = confidenceHigh
+	} else if reading.Source == sourceTelematics {
+		config, err := rdg.retrieveConfig(stub)
+		if err != nil {
+			return err
+		}
+		if !config.AllowUnsignedTelematics {
+			return newError(UNAUTHORIZED, codeSignatureRejected, "Telematics readings must be signed by their device")
+		}
+	}
+	return nil
+}
+
+//Helper: Check source override - the history is searched backwards for the latest reading of a source newReading may
+//not override; newReading must not be less in true mileage or earlier than it. Unlike the check against the current
+//reading no unit rounding is tolerated. Readings recorded before sources were introduced have none and may always be
+//overridden.
+func (rdg *ReadingAsset) checkSourceOverride(stub shim.ChaincodeStubInterface, newReading Reading) error {
+	protected := sourceOverrideRules[newReading.Source]
+	if len(protected) == 0 {
+		return nil
+	}
+	seq, err := lastSequence(stub, readingHistoryKeyType, newReading.VehicleID)
+	if err != nil {
+		return err
+	}
+	for ; seq > 0; seq-- {
+		entry, err := rdg.retrieveReadingHistoryEntry(stub, newReading.VehicleID, seq)
+		if err != nil {
+			return err
+		}
+		if !containsString(protected, entry.Source) {
+			continue
+		}
+		entryDate, err := parseReadingTime(entry.CreationDate)
+		if err != nil {
+			return err
+		}
+		newDate, err := parseReadingTime(newReading.CreationDate)
+		if err != nil {
+			return err
+		}
+		if trueKilometres(newReading) < trueKilometres(entry) || entryDate.After(newDate) {
+			return newError(CONFLICT, codeSourceConflict, "A "+newReading.Source+" reading may not be less or earlier than the latest "+
+				entry.Source+" reading of "+entry.CreationDate)
+		}
+		return nil
+	}
+	return nil
+}
+
+//filterReadingsBySource - the readings of source, in their order
+func filterReadingsBySource(readings []Reading, source string) []Reading {
+	filtered := []Reading{}
+	for _, reading := range readings {
+		if reading.Source == source {
+			filtered = append(filtered, reading)
+		}
+	}
+	return filtered
+}
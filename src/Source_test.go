@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestSource_defaultSource
+func TestSource_defaultSource(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	checkSourceOfReading(t, stub, "100001", sourceWorkshop, "", confidenceHigh)
+	stub.Creator = getCreatorForTesting("Org1MSP", "telematics")
+	res := stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100002", 70, "2017-12-01T10:15:00+01:00", ""))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"addNewReading: Reading Data is Corrupted: sourceRef: is required for telematics readings")
+	res = stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100002", 70, "2017-12-01T10:15:00+01:00", ",\"sourceRef\":\"TCU-4711\""))
+	checkErrorResponse(t, res.Status, res.Message, UNAUTHORIZED, codeSignatureRejected,
+		"addNewReading: Telematics readings must be signed by their device")
+	checkInit(t, stub, getInitForTesting(",\"allowUnsignedTelematics\":true"))
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "100002", 70, "2017-12-01T10:15:00+01:00", ",\"sourceRef\":\"TCU-4711\""))
+	checkSourceOfReading(t, stub, "100002", sourceTelematics, "TCU-4711", confidenceMedium)
+}
+
+//TestSource_sourceRoles
+func TestSource_sourceRoles(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001")
+	checkUnauthorized(t, stub, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00",
+		",\"source\":\"inspection\",\"sourceRef\":\"TS-0042\""),
+		"addNewReading: Role workshop may not submit inspection readings - requires one of: inspector")
+	stub.Creator = getCreatorForTesting("Org1MSP", "inspector")
+	checkUnauthorized(t, stub, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"source\":\"manual\""),
+		"addNewReading: Role inspector may not submit manual readings - requires one of: workshop, registry")
+	res := stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"source\":\"garage\""))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Reading Data is Corrupted: source: must be one of: manual, workshop, inspection, telematics, registry")
+	res = stub.MockInvoke("1", getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"confidence\":\"high\""))
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"Reading Data is Corrupted: confidence: is set by the chaincode and must not be sent")
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"sourceRef\":\"TS-0042\""))
+	checkSourceOfReading(t, stub, "100001", sourceInspection, "TS-0042", confidenceHigh)
+}
+
+//TestSource_sourceOverride
+func TestSource_sourceOverride(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "inspector")
+	checkInitWithPurgeConfirmation(t, stub)
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ",\"sourceRef\":\"TS-0042\""))
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	res := stub.MockInvoke("1", getReadingForOdometerTesting("updateReading", "\"reading\":31,\"unit\":\"mi\",\"source\":\"manual\"",
+		"2017-12-10T10:15:00+01:00"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeSourceConflict,
+		"updateReading: A manual reading may not be less or earlier than the latest inspection reading of 2017-12-01T09:15:00.000Z")
+	checkSourceOfReading(t, stub, "100001", sourceInspection, "TS-0042", confidenceHigh)
+	checkInvoke(t, stub, getGeoReadingForTesting("updateReading", "100001", 60, "2017-12-10T10:15:00+01:00", ""))
+	checkInvoke(t, stub, getGeoReadingForTesting("updateReading", "100001", 70, "2017-12-15T10:15:00+01:00", ",\"source\":\"manual\""))
+	checkSourceOfReading(t, stub, "100001", sourceManual, "", confidenceLow)
+	checkInvoke(t, stub, getGeoReadingForTesting("addNewReading", "100002", 60, "2017-12-10T10:15:00+01:00", ""))
+	checkInvoke(t, stub, getGeoReadingForTesting("updateReading", "100002", 70, "2017-12-15T10:15:00+01:00", ",\"source\":\"manual\""))
+	checkSourceOfReading(t, stub, "100002", sourceManual, "", confidenceLow)
+	checkReadingHistorySources(t, stub, "", []string{sourceInspection, sourceWorkshop, sourceManual})
+	checkReadingHistorySources(t, stub, sourceInspection, []string{sourceInspection})
+	checkReadingHistorySources(t, stub, sourceTelematics, []string{})
+	res = stub.MockInvoke("1", [][]byte{[]byte("readReadingHistory"), []byte("100001"), []byte("garage")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeBadRequest,
+		"readReadingHistory: Unknown source garage - expects one of: manual, workshop, inspection, telematics, registry")
+
+	stub.Creator = getCreatorForTesting("Org1MSP", "inspector")
+	checkInvoke(t, stub, getGeoReadingForTesting("updateReading", "100001", 100, "2017-12-20T10:15:00+01:00", ",\"sourceRef\":\"TS-0042\""))
+	stub.Creator = getCreatorForTesting("Org1MSP", "admin")
+	checkRemoveAllReadings(t, stub)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	res = stub.MockInvoke("1", getReadingForOdometerTesting("addNewReading", "\"reading\":62,\"unit\":\"mi\",\"source\":\"manual\"",
+		"2017-12-21T10:15:00+01:00"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeSourceConflict,
+		"addNewReading: A manual reading may not be less or earlier than the latest inspection reading of 2017-12-20T09:15:00.000Z")
+	checkInvoke(t, stub, getReadingForOdometerTesting("addNewReading", "\"reading\":63,\"unit\":\"mi\",\"source\":\"manual\"",
+		"2017-12-21T10:15:00+01:00"))
+	checkSourceOfReading(t, stub, "100001", sourceManual, "", confidenceLow)
+}
+
+//TestSource_queryReadingsNear
+func TestSource_queryReadingsNear(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkLocatedReading(t, stub, "1", 52.52, 13.405, getGeoReadingForTesting("addNewReading", "100001", 50, "2017-12-01T10:15:00+01:00", ""))
+	stub.Creator = getCreatorForTesting("Org1MSP", "inspector")
+	checkLocatedReading(t, stub, "tx2", 52.5201, 13.4049, getGeoReadingForTesting("addNewReading", "100002", 70, "2017-12-01T10:15:00+01:00",
+		",\"sourceRef\":\"TS-0042\""))
+	bounds := "\"minLatitude\":52.5,\"minLongitude\":13.4,\"maxLatitude\":52.53,\"maxLongitude\":13.41"
+	checkQueryReadingsNear(t, stub, "{"+bounds+"}", []string{"100001/1", "100002/tx2"})
+	checkQueryReadingsNear(t, stub, "{"+bounds+",\"source\":\"inspection\"}", []string{"100002/tx2"})
+	res := stub.MockInvoke("1", [][]byte{[]byte("queryReadingsNear"), []byte("{" + bounds + ",\"source\":\"garage\"}")})
+	checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed,
+		"queryReadingsNear: Query is Corrupted: source: must be one of: manual, workshop, inspection, telematics, registry")
+}
+
+//checkSourceOfReading - helper checking source, sourceRef and confidence of the current reading of a vehicle
+func checkSourceOfReading(t *testing.T, stub *ExtendedMockStub, vehicleID string, source string, sourceRef string, confidence string) {
+	res := stub.MockInvoke("1", [][]byte{[]byte("readReading"), []byte(vehicleID)})
+	var reading Reading
+	err := json.Unmarshal(res.Payload, &reading)
+	if err != nil || reading.Source != source || reading.SourceRef != sourceRef || reading.Confidence != confidence {
+		fmt.Println("Reading of", vehicleID, "expected source", source, sourceRef, confidence, "Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+}
+
+//checkReadingHistorySources - helper checking the sources of the reading history of vehicle 100001, filtered by source
+func checkReadingHistorySources(t *testing.T, stub *ExtendedMockStub, source string, expected []string) {
+	args := [][]byte{[]byte("readReadingHistory"), []byte("100001")}
+	if source != "" {
+		args = append(args, []byte(source))
+	}
+	res := stub.MockInvoke("1", args)
+	var readings []Reading
+	err := json.Unmarshal(res.Payload, &readings)
+	if res.Status != shim.OK || err != nil || len(readings) != len(expected) {
+		fmt.Println("func readReadingHistory", source, "Expected:", expected, "Actual:", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	for i, reading := range readings {
+		if reading.Source != expected[i] {
+			fmt.Println("func readReadingHistory", source, "Expected:", expected, "Actual:", string(res.Payload))
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//suspiciousKeyType - object type of the composite keys suspicious~vehicle~seq of the suspicious readings
+const suspiciousKeyType = "suspicious~vehicle~seq"
+
+//SuspiciousReading - Rejected update of the reading of a vehicle, kept as evidence if recordSuspiciousReadings is
+//configured. Stored under the composite key suspicious~vehicle~seq. Rejected is always true: returned as payload of a
+//successful updateReading, it tells the caller the reading was not stored.
+type SuspiciousReading struct {
+	ObjectType   string        `json:"docType"`
+	VehicleID    string        `json:"vehicleID"`
+	Sequence     int           `json:"seq"`
+	Rejected     bool          `json:"rejected"`
+	Code         string        `json:"code"`
+	Reason       string        `json:"reason"`
+	ClaimedValue OdometerValue `json:"claimedValue"`
+	ClaimedUnit  string        `json:"claimedUnit"`
+	ClaimedDate  string        `json:"claimedDate"`
+	CurrentValue OdometerValue `json:"currentValue"`
+	CurrentUnit  string        `json:"currentUnit"`
+	CurrentDate  string        `json:"currentDate"`
+	Submitter    Submitter     `json:"submitter"`
+	TxID         string        `json:"txID"`
+	TxTimestamp  string        `json:"txTimestamp"`
+}
+
+//rejectionReasons - reasons of the ReadingRejected event by the code of a rejected reading update
+var rejectionReasons = map[string]string{
+	codeRollbackDetected:   "Reading rollback",
+	codeDateRegression:     "Date rollback",
+	codeImplausibleReading: "Implausible mileage growth",
+}
+
+//rejectionReason - the reason if err rejects a reading update as suspicious, false for any other error
+func rejectionReason(err error) (string, bool) {
+	chaincodeError, ok := err.(ChaincodeError)
+	if !ok {
+		return "", false
+	}
+	reason, ok := rejectionReasons[chaincodeError.Code]
+	return reason, ok
+}
+
+//Helper: Reject newReading - returns rejection. If recordSuspiciousReadings is configured, the attempt is saved as
+//SuspiciousReading and returned as payload of a successful response instead, so the transaction is committed; its
+//rejected flag, code and reason tell the caller the reading was not stored. Only then is the ReadingRejected event set,
+//as peers do not deliver the events of failed proposals.
+func (rdg *ReadingAsset) rejectReading(stub shim.ChaincodeStubInterface, currReading Reading, newReading Reading, reason string,
+	rejection ChaincodeError) peer.Response {
+	config, err := rdg.retrieveConfig(stub)
+	if err != nil || !config.RecordSuspiciousReadings {
+		return errorResponse(rejection)
+	}
+	bytes, err := rdg.recordSuspiciousReading(stub, currReading, newReading, reason, rejection.Code)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = setReadingEvent(stub, eventReadingRejected, currReading, newReading, reason)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(bytes)
+}
+
+//Helper: Record suspicious reading - saves the rejected update from currReading to newReading, returns the stored JSON.
+//The counter of a signed newReading is used up like that of a stored reading.
+func (rdg *ReadingAsset) recordSuspiciousReading(stub shim.ChaincodeStubInterface, currReading Reading, newReading Reading,
+	reason string, code string) ([]byte, error) {
+	suspicious := SuspiciousReading{
+		ObjectType:   "Asset.SuspiciousReading",
+		VehicleID:    newReading.VehicleID,
+		Rejected:     true,
+		Code:         code,
+		Reason:       reason,
+		ClaimedValue: newReading.Reading,
+		ClaimedUnit:  newReading.Unit,
+		ClaimedDate:  newReading.CreationDate,
+		CurrentValue: currReading.Reading,
+		CurrentUnit:  currReading.Unit,
+		CurrentDate:  currReading.CreationDate,
+		TxID:         stub.GetTxID(),
+	}
+	_, err := rdg.saveSignedCounter(stub, newReading)
+	if err != nil {
+		return nil, err
+	}
+	return rdg.saveSuspiciousReading(stub, suspicious)
+}
+
+//Query Route: readSuspiciousReadings - all rejected updates recorded for a vehicle, oldest first
+func (rdg *ReadingAsset) readSuspiciousReadings(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	suspicious, err := rdg.retrieveSuspiciousReadings(stub, vehicleID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if len(suspicious) == 0 {
+		return notFound("readSuspiciousReadings: No suspicious readings found for vehicle with ID: " + vehicleID)
+	}
+	bytes, err := json.Marshal(suspicious)
+	if err != nil {
+		return internalError("readSuspiciousReadings: Error marshalling suspicious readings JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//Helper: Save suspicious reading - stamps the submitter and the next sequence number, returns the stored JSON
+func (rdg *ReadingAsset) saveSuspiciousReading(stub shim.ChaincodeStubInterface, suspicious SuspiciousReading) ([]byte, error) {
+	var err error
+	suspicious.Sequence, err = nextSequence(stub, suspiciousKeyType, suspicious.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+	suspicious.Submitter, err = getSubmitter(stub)
+	if err != nil {
+		return nil, errors.New("saveSuspiciousReading: " + err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return nil, errors.New("saveSuspiciousReading: " + err.Error())
+	}
+	suspicious.TxTimestamp = txTime.Format(time.RFC3339Nano)
+	suspiciousKey, err := createSequenceKey(stub, suspiciousKeyType, suspicious.VehicleID, suspicious.Sequence)
+	if err != nil {
+		return nil, errors.New("saveSuspiciousReading: Error creating suspicious reading key for vehicle with ID: " + suspicious.VehicleID)
+	}
+	bytes, err := json.Marshal(suspicious)
+	if err != nil {
+		return nil, errors.New("saveSuspiciousReading: Error converting suspicious reading record JSON")
+	}
+	err = stub.PutState(suspiciousKey, bytes)
+	if err != nil {
+		return nil, errors.New("saveSuspiciousReading: Error storing suspicious reading record")
+	}
+	return bytes, nil
+}
+
+//Helper: Retrieve suspicious readings - the suspicious readings recorded for a vehicle, oldest first
+func (rdg *ReadingAsset) retrieveSuspiciousReadings(stub shim.ChaincodeStubInterface, vehicleID string) ([]SuspiciousReading, error) {
+	records := []SuspiciousReading{}
+	values, err := retrieveRecordValues(stub, suspiciousKeyType, vehicleID)
+	if err != nil {
+		return records, err
+	}
+	for _, value := range values {
+		var suspicious SuspiciousReading
+		err = json.Unmarshal(value, &suspicious)
+		if err != nil {
+			return records, errors.New("retrieveSuspiciousReadings: Corrupt suspicious reading record " + string(value))
+		}
+		records = append(records, suspicious)
+	}
+	return records, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestSuspicious_notRecordedByDefault
+func TestSuspicious_notRecordedByDefault(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getUpdateReadingAssetForValueNOKTesting())
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeRollbackDetected,
+		"updateReading: New Reading is less than Current Reading - cannot update")
+	res = stub.MockInvoke("1", [][]byte{[]byte("readSuspiciousReadings"), []byte("100001")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeNotFound,
+		"readSuspiciousReadings: No suspicious readings found for vehicle with ID: 100001")
+}
+
+//TestSuspicious_recordSuspiciousReadings
+func TestSuspicious_recordSuspiciousReadings(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(",\"recordSuspiciousReadings\":true"))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+	res := stub.MockInvoke("1", getUpdateReadingAssetForValueNOKTesting())
+	if res.Status != shim.OK || res.Message != "" {
+		fmt.Println("Expected the recorded suspicious reading as success, Actual:", res.Status, res.Message)
+		t.FailNow()
+	}
+	checkReadingEvent(t, stub, eventReadingRejected,
+		ReadingEvent{VehicleID: "100001", OldValue: 50, OldUnit: "km", NewValue: 20, NewUnit: "km", Reason: "Reading rollback"})
+	var suspicious SuspiciousReading
+	err := json.Unmarshal(res.Payload, &suspicious)
+	if err != nil || !suspicious.Rejected || suspicious.Code != codeRollbackDetected || suspicious.Sequence != 1 ||
+		suspicious.ClaimedValue != 20 || suspicious.CurrentValue != 50 {
+		fmt.Println("Expected SuspiciousReading as payload, Actual:", string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", getUpdateReadingAssetForDateNOKTesting())
+	err = json.Unmarshal(res.Payload, &suspicious)
+	if res.Status != shim.OK || err != nil || !suspicious.Rejected || suspicious.Code != codeDateRegression {
+		fmt.Println("Expected the recorded suspicious reading as payload, Actual:", res.Status, res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	checkState(t, stub, "100001", getNewReadingExpected())
+	res = stub.MockInvoke("1", [][]byte{[]byte("readSuspiciousReadings"), []byte("100001")})
+	var records []SuspiciousReading
+	err = json.Unmarshal(res.Payload, &records)
+	if err != nil || len(records) != 2 {
+		fmt.Println("func readSuspiciousReadings failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	expected := SuspiciousReading{ObjectType: "Asset.SuspiciousReading", VehicleID: "100001", Sequence: 2, Rejected: true, Code: codeDateRegression,
+		Reason: "Date rollback", ClaimedValue: 100, ClaimedUnit: "km", ClaimedDate: "2017-11-20T12:00:00.000Z", CurrentValue: 50,
+		CurrentUnit: "km", CurrentDate: "2017-12-01T09:15:00.000Z",
+		Submitter: Submitter{MSPID: "Org1MSP", Subject: "CN=workshop@Org1MSP,O=Org1MSP"},
+		TxID:      "1", TxTimestamp: defaultTxTimeForTesting.Format("2006-01-02T15:04:05Z07:00")}
+	if records[1] != expected {
+		fmt.Println("Unexpected suspicious reading\nExpected:", expected, "\nActual  :", records[1])
+		t.FailNow()
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//idPattern - IDs are 1 to 64 letters, digits, dots, underscores or hyphens, starting with a letter or digit
+var idPattern = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$")
+
+//FieldError - one offending field of an input JSON object
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//ValidationError - every offending field of an input JSON object
+type ValidationError struct {
+	Fields []FieldError
+}
+
+//Error - lists every offending field
+func (err ValidationError) Error() string {
+	messages := make([]string, 0, len(err.Fields))
+	for _, field := range err.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+//fieldValidator - returns why the raw JSON value of a field is invalid, "" if it is valid
+type fieldValidator func(value json.RawMessage) string
+
+//fieldSchema - schema of one field of an input JSON object
+type fieldSchema struct {
+	name     string
+	required bool
+	validate fieldValidator
+}
+
+//validateInput - checks an input JSON object against the schema: unknown fields are disallowed, required fields
+//must be present and every present field must be valid. All offending fields are reported at once.
+func validateInput(input string, schema []fieldSchema) error {
+	var fields map[string]json.RawMessage
+	decoder := json.NewDecoder(strings.NewReader(input))
+	err := decoder.Decode(&fields)
+	if err != nil || fields == nil || decoder.More() {
+		return ValidationError{Fields: []FieldError{{Field: "$", Message: "must be a single JSON object"}}}
+	}
+	var fieldErrors []FieldError
+	known := make(map[string]bool)
+	for _, field := range schema {
+		known[field.name] = true
+		value, present := fields[field.name]
+		if !present {
+			if field.required {
+				fieldErrors = append(fieldErrors, FieldError{Field: field.name, Message: "is required"})
+			}
+			continue
+		}
+		message := field.validate(value)
+		if message != "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.name, Message: message})
+		}
+	}
+	var unknown []string
+	for name := range fields {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		fieldErrors = append(fieldErrors, FieldError{Field: name, Message: "is not a known field"})
+	}
+	if len(fieldErrors) > 0 {
+		return ValidationError{Fields: fieldErrors}
+	}
+	return nil
+}
+
+//stringField - validator for a JSON string, checked further by check (may be nil)
+func stringField(check func(value string) string) fieldValidator {
+	return func(value json.RawMessage) string {
+		var text string
+		if len(value) == 0 || value[0] != '"' || json.Unmarshal(value, &text) != nil {
+			return "must be a string"
+		}
+		if check == nil {
+			return ""
+		}
+		return check(text)
+	}
+}
+
+//numberField - validator for a JSON number, checked further by check (may be nil)
+func numberField(check func(value float64) string) fieldValidator {
+	return func(value json.RawMessage) string {
+		var number float64
+		trimmed := bytes.TrimSpace(value)
+		if len(trimmed) == 0 || !(trimmed[0] == '-' || (trimmed[0] >= '0' && trimmed[0] <= '9')) ||
+			json.Unmarshal(trimmed, &number) != nil {
+			return "must be a number"
+		}
+		if check == nil {
+			return ""
+		}
+		return check(number)
+	}
+}
+
+//checkID - IDs must match idPattern
+func checkID(value string) string {
+	if !idPattern.MatchString(value) {
+		return "must be 1 to 64 characters of A-Z, a-z, 0-9, '.', '_' or '-', starting with a letter or digit"
+	}
+	return ""
+}
+
+//checkEnum - the value must be one of values
+func checkEnum(values ...string) func(value string) string {
+	return func(value string) string {
+		if !containsString(values, value) {
+			return "must be one of: " + strings.Join(values, ", ")
+		}
+		return ""
+	}
+}
+
+//checkText - free text of 1 to maxLength characters
+func checkText(maxLength int) func(value string) string {
+	return func(value string) string {
+		if value == "" || utf8.RuneCountInString(value) > maxLength {
+			return "must be 1 to " + strconv.Itoa(maxLength) + " characters"
+		}
+		return ""
+	}
+}
+
+//checkRange - the number must lie between min and max, both included
+func checkRange(min float64, max float64) func(value float64) string {
+	return func(value float64) string {
+		if value < min || value > max {
+			return "must be between " + strconv.FormatFloat(min, 'f', -1, 64) + " and " + strconv.FormatFloat(max, 'f', -1, 64)
+		}
+		return ""
+	}
+}
+
+//checkTimestamp - the value must be an RFC 3339 timestamp with time zone offset
+func checkTimestamp(value string) string {
+	_, err := normalizeReadingTime(value)
+	if err != nil {
+		return "must be an RFC 3339 timestamp with time zone offset, e.g. 2017-12-01T10:15:00+01:00"
+	}
+	return ""
+}
+
+//checkBase64 - the value must be standard base64 encoded, with padding
+func checkBase64(value string) string {
+	_, err := base64.StdEncoding.DecodeString(value)
+	if value == "" || err != nil {
+		return "must be base64 encoded"
+	}
+	return ""
+}
+
+//readOnlyField - validator for fields set by the chaincode, which clients must not send
+func readOnlyField(value json.RawMessage) string {
+	return "is set by the chaincode and must not be sent"
+}
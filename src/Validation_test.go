@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//TestValidation_getReadingFromArgs
+func TestValidation_getReadingFromArgs(t *testing.T) {
+	tests := map[string]string{
+		"{\"vehicleID\":\"x\\\"reading\\\"\",\"docType\":\"Asset.Reading\",\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00Z\"}": "vehicleID: must be 1 to 64 characters of A-Z, a-z, 0-9, '.', '_' or '-', starting with a letter or digit; " +
+			"reading: is required",
+		"{\"vehicleID\":100001,\"docType\":\"Asset.Vehicle\",\"reading\":50,\"unit\":\"km\",\"creationDate\":\"12/01/2017\",\"txID\":\"1\",\"color\":\"red\"}": "vehicleID: must be a string; docType: must be one of: Asset.Reading; " +
+			"creationDate: must be an RFC 3339 timestamp with time zone offset, e.g. 2017-12-01T10:15:00+01:00; " +
+			"txID: is set by the chaincode and must not be sent; color: is not a known field",
+		"[]":    "$: must be a single JSON object",
+		"{} {}": "$: must be a single JSON object",
+		"{\"vehicleID\":\"ABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLM\",\"docType\":\"Asset.Reading\",\"reading\":50,\"unit\":\"km\",\"creationDate\":\"2017-12-01T10:15:00Z\"}": "vehicleID: must be 1 to 64 characters of A-Z, a-z, 0-9, '.', '_' or '-', starting with a letter or digit",
+	}
+	for input, expectedErr := range tests {
+		_, err := getReadingFromArgs([]string{input})
+		if err == nil {
+			fmt.Println("getReadingFromArgs of", input, "was expected to fail")
+			t.FailNow()
+		}
+		checkError(t, expectedErr, err.Error())
+	}
+	_, err := getReadingFromArgs([]string{})
+	if err == nil {
+		fmt.Println("getReadingFromArgs without arguments was expected to fail")
+		t.FailNow()
+	}
+}
+
+//TestValidation_addNewReadingFieldErrors
+func TestValidation_addNewReadingFieldErrors(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	res := stub.MockInvoke("1", [][]byte{[]byte("addNewReading"),
+		[]byte("{\"vehicleID\":\"\",\"docType\":\"Asset.Reading\",\"reading\":true,\"creationDate\":\"2017-12-01T10:15:00Z\"}")})
+	if res.Status == shim.OK {
+		fmt.Println("addNewReading with invalid fields was expected to fail")
+		t.FailNow()
+	}
+	checkError(t, "Reading Data is Corrupted: vehicleID: must be 1 to 64 characters of A-Z, a-z, 0-9, '.', '_' or '-', "+
+		"starting with a letter or digit; reading: must be a number; unit: is required", res.Message)
+}
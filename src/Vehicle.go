@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+//Object types of the composite keys of vehicles and of the VIN index
+const (
+	vehicleKeyType  = "vehicle~vehicleID"
+	vinIndexKeyType = "vin~vehicleID"
+)
+
+//vinPattern - ISO 3779: 17 digits or capital letters except I, O and Q
+var vinPattern = regexp.MustCompile("^[A-HJ-NPR-Z0-9]{17}$")
+
+//countryPattern - ISO 3166-1 alpha-2 country codes
+var countryPattern = regexp.MustCompile("^[A-Z]{2}$")
+
+//checkDigitCountries - registration countries requiring the North American check digit in position 9 of the VIN
+var checkDigitCountries = []string{"US", "CA", "MX"}
+
+//Model years of vehicles - the 17 character VIN is standardized since 1981
+const (
+	minModelYear = 1981
+	maxModelYear = 2100
+)
+
+//Vehicle - Details of the asset type Vehicle, stored under the composite key vehicle~vehicleID
+type Vehicle struct {
+	VehicleID           string `json:"vehicleID"`
+	ObjectType          string `json:"docType"`
+	VIN                 string `json:"vin"`
+	Make                string `json:"make"`
+	Model               string `json:"model"`
+	Year                int    `json:"year"`
+	RegistrationCountry string `json:"registrationCountry"`
+	OwnerID             string `json:"ownerID,omitempty"`
+	Category            string `json:"category,omitempty"`
+	OwnerDetailsHash    string `json:"ownerDetailsHash,omitempty"`
+	SubmitterMSPID      string `json:"submitterMSPID"`
+	SubmitterSubject    string `json:"submitterSubject"`
+	TxID                string `json:"txID"`
+	TxTimestamp         string `json:"txTimestamp"`
+}
+
+//vehicleSchema - schema of the Vehicle JSON accepted by registerVehicle and updateVehicle
+var vehicleSchema = []fieldSchema{
+	{name: "vehicleID", required: true, validate: stringField(checkID)},
+	{name: "docType", required: true, validate: stringField(checkEnum("Asset.Vehicle"))},
+	{name: "vin", required: true, validate: stringField(checkVIN)},
+	{name: "make", required: true, validate: stringField(checkText(64))},
+	{name: "model", required: true, validate: stringField(checkText(64))},
+	{name: "year", required: true, validate: numberField(checkModelYear)},
+	{name: "registrationCountry", required: true, validate: stringField(checkCountry)},
+	{name: "ownerID", validate: stringField(checkID)},
+	{name: "category", validate: stringField(checkID)},
+	{name: "ownerDetailsHash", validate: readOnlyField},
+	{name: "submitterMSPID", validate: readOnlyField},
+	{name: "submitterSubject", validate: readOnlyField},
+	{name: "txID", validate: readOnlyField},
+	{name: "txTimestamp", validate: readOnlyField},
+}
+
+//Invoke Route: registerVehicle - a VIN can only be registered for one vehicle; the first owner is optional. The
+//transient field ownerDetails may carry the owner's personal data, which is stored in collectionOwnerDetails.
+func (rdg *ReadingAsset) registerVehicle(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, vehicleRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("registerVehicle: ", err))
+	}
+	vehicle, err := getVehicleFromArgs(args)
+	if err != nil {
+		return validationFailed("registerVehicle: Vehicle Data is Corrupted", err)
+	}
+	ownerDetails, err := getOwnerDetailsFromTransient(stub, vehicle)
+	if err != nil {
+		return validationFailed("registerVehicle: Owner Details are Corrupted", err)
+	}
+	_, err = rdg.retrieveVehicle(stub, vehicle.VehicleID)
+	if err == nil {
+		return conflict(codeAlreadyExists, "registerVehicle: This Vehicle already exists: "+vehicle.VehicleID)
+	}
+	if chaincodeError, ok := err.(ChaincodeError); !ok || chaincodeError.Code != codeVehicleNotRegistered {
+		return errorResponse(err)
+	}
+	err = rdg.checkVINAvailable(stub, vehicle)
+	if err != nil {
+		return errorResponse(withPrefix("registerVehicle: ", err))
+	}
+	err = stampVehicle(stub, &vehicle)
+	if err != nil {
+		return errorResponse(withPrefix("registerVehicle: ", err))
+	}
+	if ownerDetails != nil {
+		vehicle.OwnerDetailsHash, err = privateDataHash(*ownerDetails)
+		if err != nil {
+			return errorResponse(withPrefix("registerVehicle: ", err))
+		}
+		_, err = rdg.saveOwnerDetails(stub, *ownerDetails)
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+	_, err = rdg.saveVehicle(stub, vehicle)
+	if err != nil {
+		return errorResponse(err)
+	}
+	_, err = rdg.updateVINIndex(stub, vehicle)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+//Invoke Route: updateVehicle - replaces the details of a registered vehicle, a corrected VIN must not be registered
+//for another vehicle. Owner details sent in the transient field ownerDetails replace the stored ones.
+func (rdg *ReadingAsset) updateVehicle(stub shim.ChaincodeStubInterface, args []string) peer.Response {
+	err := rdg.checkAccess(stub, vehicleRoles...)
+	if err != nil {
+		return errorResponse(withPrefix("updateVehicle: ", err))
+	}
+	newVehicle, err := getVehicleFromArgs(args)
+	if err != nil {
+		return validationFailed("updateVehicle: Vehicle Data is Corrupted", err)
+	}
+	currVehicle, err := rdg.retrieveVehicle(stub, newVehicle.VehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("updateVehicle: ", err))
+	}
+	if newVehicle.OwnerID != "" && newVehicle.OwnerID != currVehicle.OwnerID {
+		return validationFailed("updateVehicle: Vehicle Data is Corrupted",
+			ValidationError{Fields: []FieldError{{Field: "ownerID", Message: "can only be changed by transferOwnership"}}})
+	}
+	newVehicle.OwnerID = currVehicle.OwnerID
+	newVehicle.OwnerDetailsHash = currVehicle.OwnerDetailsHash
+	ownerDetails, err := getOwnerDetailsFromTransient(stub, newVehicle)
+	if err != nil {
+		return validationFailed("updateVehicle: Owner Details are Corrupted", err)
+	}
+	if newVehicle.VIN != currVehicle.VIN {
+		err = rdg.checkVINAvailable(stub, newVehicle)
+		if err != nil {
+			return errorResponse(withPrefix("updateVehicle: ", err))
+		}
+		_, err = rdg.deleteVINIndex(stub, currVehicle)
+		if err != nil {
+			return errorResponse(err)
+		}
+		_, err = rdg.updateVINIndex(stub, newVehicle)
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+	err = stampVehicle(stub, &newVehicle)
+	if err != nil {
+		return errorResponse(withPrefix("updateVehicle: ", err))
+	}
+	if ownerDetails != nil {
+		newVehicle.OwnerDetailsHash, err = privateDataHash(*ownerDetails)
+		if err != nil {
+			return errorResponse(withPrefix("updateVehicle: ", err))
+		}
+		_, err = rdg.saveOwnerDetails(stub, *ownerDetails)
+		if err != nil {
+			return errorResponse(err)
+		}
+	}
+	_, err = rdg.saveVehicle(stub, newVehicle)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+//Query Route: readVehicle
+func (rdg *ReadingAsset) readVehicle(stub shim.ChaincodeStubInterface, vehicleID string) peer.Response {
+	vehicle, err := rdg.retrieveVehicle(stub, vehicleID)
+	if err != nil {
+		return errorResponse(withPrefix("readVehicle: ", err))
+	}
+	bytes, err := json.Marshal(vehicle)
+	if err != nil {
+		return internalError("readVehicle: Error marshalling vehicle JSON")
+	}
+	return shim.Success(bytes)
+}
+
+//Helper: Save vehicle
+func (rdg *ReadingAsset) saveVehicle(stub shim.ChaincodeStubInterface, vehicle Vehicle) (bool, error) {
+	vehicleKey, err := stub.CreateCompositeKey(vehicleKeyType, []string{vehicle.VehicleID})
+	if err != nil {
+		return false, errors.New("saveVehicle: Error creating vehicle key for vehicle with ID: " + vehicle.VehicleID)
+	}
+	bytes, err := json.Marshal(vehicle)
+	if err != nil {
+		return false, errors.New("saveVehicle: Error converting vehicle record JSON")
+	}
+	err = stub.PutState(vehicleKey, bytes)
+	if err != nil {
+		return false, errors.New("saveVehicle: Error storing vehicle record")
+	}
+	return true, nil
+}
+
+//Helper: Retrieve vehicle - VEHICLE_NOT_REGISTERED if no vehicle is registered with the ID
+func (rdg *ReadingAsset) retrieveVehicle(stub shim.ChaincodeStubInterface, vehicleID string) (Vehicle, error) {
+	var vehicle Vehicle
+	vehicleKey, err := stub.CreateCompositeKey(vehicleKeyType, []string{vehicleID})
+	if err != nil {
+		return vehicle, errors.New("retrieveVehicle: Error creating vehicle key for vehicle with ID: " + vehicleID)
+	}
+	bytes, err := stub.GetState(vehicleKey)
+	if err != nil {
+		return vehicle, errors.New("retrieveVehicle: Error retrieving vehicle with ID: " + vehicleID)
+	}
+	if bytes == nil {
+		return vehicle, newError(NOTFOUND, codeVehicleNotRegistered, "No vehicle registered with ID: "+vehicleID)
+	}
+	err = json.Unmarshal(bytes, &vehicle)
+	if err != nil {
+		return vehicle, errors.New("retrieveVehicle: Corrupt vehicle record " + string(bytes))
+	}
+	return vehicle, nil
+}
+
+//Helper: Check no other vehicle is registered with the VIN of vehicle
+func (rdg *ReadingAsset) checkVINAvailable(stub shim.ChaincodeStubInterface, vehicle Vehicle) error {
+	indexKey, err := stub.CreateCompositeKey(vinIndexKeyType, []string{vehicle.VIN})
+	if err != nil {
+		return errors.New("Error creating VIN index key for VIN: " + vehicle.VIN)
+	}
+	bytes, err := stub.GetState(indexKey)
+	if err != nil {
+		return errors.New("Error getting VIN " + vehicle.VIN + " from VIN index")
+	}
+	if bytes != nil && string(bytes) != vehicle.VehicleID {
+		return newError(CONFLICT, codeAlreadyExists, "VIN "+vehicle.VIN+" is already registered for vehicle with ID: "+string(bytes))
+	}
+	return nil
+}
+
+//Helper: Update VIN index - one composite key vin~vehicleID per VIN holding the ID of its vehicle
+func (rdg *ReadingAsset) updateVINIndex(stub shim.ChaincodeStubInterface, vehicle Vehicle) (bool, error) {
+	indexKey, err := stub.CreateCompositeKey(vinIndexKeyType, []string{vehicle.VIN})
+	if err != nil {
+		return false, errors.New("updateVINIndex: Error creating VIN index key for VIN: " + vehicle.VIN)
+	}
+	err = stub.PutState(indexKey, []byte(vehicle.VehicleID))
+	if err != nil {
+		return false, errors.New("updateVINIndex: Error storing VIN in VIN index")
+	}
+	return true, nil
+}
+
+//Helper: Delete the VIN of vehicle from the VIN index
+func (rdg *ReadingAsset) deleteVINIndex(stub shim.ChaincodeStubInterface, vehicle Vehicle) (bool, error) {
+	indexKey, err := stub.CreateCompositeKey(vinIndexKeyType, []string{vehicle.VIN})
+	if err != nil {
+		return false, errors.New("deleteVINIndex: Error creating VIN index key for VIN: " + vehicle.VIN)
+	}
+	err = stub.DelState(indexKey)
+	if err != nil {
+		return false, errors.New("deleteVINIndex: Error deleting VIN from VIN index")
+	}
+	return true, nil
+}
+
+//stampVehicle - records the submitting identity and the transaction on the vehicle
+func stampVehicle(stub shim.ChaincodeStubInterface, vehicle *Vehicle) error {
+	submitter, err := getSubmitter(stub)
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	vehicle.SubmitterMSPID = submitter.MSPID
+	vehicle.SubmitterSubject = submitter.Subject
+	vehicle.TxID = stub.GetTxID()
+	vehicle.TxTimestamp = txTime.Format(time.RFC3339Nano)
+	return nil
+}
+
+//getVehicleFromArgs - construct a vehicle structure from string array of arguments
+func getVehicleFromArgs(args []string) (vehicle Vehicle, err error) {
+	if len(args) != 1 {
+		return vehicle, ValidationError{Fields: []FieldError{{Field: "$", Message: "expects exactly one Vehicle JSON argument"}}}
+	}
+	err = validateInput(args[0], vehicleSchema)
+	if err != nil {
+		return vehicle, err
+	}
+	err = json.Unmarshal([]byte(args[0]), &vehicle)
+	if err != nil {
+		return vehicle, err
+	}
+	if containsString(checkDigitCountries, vehicle.RegistrationCountry) || isNorthAmericanVIN(vehicle.VIN) {
+		expected := vinCheckDigit(vehicle.VIN)
+		if vehicle.VIN[8] != expected {
+			return vehicle, ValidationError{Fields: []FieldError{{Field: "vin",
+				Message: "check digit " + vehicle.VIN[8:9] + " in position 9 does not match, expected " + string(expected)}}}
+		}
+	}
+	return vehicle, nil
+}
+
+//checkVIN - VINs must match vinPattern; the check digit depends on the registration country and is checked later
+func checkVIN(value string) string {
+	if !vinPattern.MatchString(value) {
+		return "must be 17 characters of 0-9 and A-Z except I, O and Q"
+	}
+	return ""
+}
+
+//checkModelYear - model years are whole numbers from minModelYear to maxModelYear
+func checkModelYear(value float64) string {
+	if value != math.Trunc(value) || value < minModelYear || value > maxModelYear {
+		return "must be a year from " + strconv.Itoa(minModelYear) + " to " + strconv.Itoa(maxModelYear)
+	}
+	return ""
+}
+
+//checkCountry - registration countries are ISO 3166-1 alpha-2 codes
+func checkCountry(value string) string {
+	if !countryPattern.MatchString(value) {
+		return "must be an ISO 3166-1 alpha-2 country code, e.g. DE"
+	}
+	return ""
+}
+
+//isNorthAmericanVIN - the world manufacturer identifier of vehicles built in North America starts with 1 to 5
+func isNorthAmericanVIN(vin string) bool {
+	return vin[0] >= '1' && vin[0] <= '5'
+}
+
+//vinCheckDigit - North American check digit of a VIN: the weighted sum of the transliterated characters modulo 11,
+//X for 10
+func vinCheckDigit(vin string) byte {
+	weights := []int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+	sum := 0
+	for i := 0; i < len(weights) && i < len(vin); i++ {
+		sum += vinTransliteration(vin[i]) * weights[i]
+	}
+	remainder := sum % 11
+	if remainder == 10 {
+		return 'X'
+	}
+	return byte('0' + remainder)
+}
+
+//vinTransliteration - numeric value of a VIN character
+func vinTransliteration(char byte) int {
+	if char >= '0' && char <= '9' {
+		return int(char - '0')
+	}
+	return map[byte]int{
+		'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+		'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+		'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+	}[char]
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+//TestVehicle_vinCheckDigit
+func TestVehicle_vinCheckDigit(t *testing.T) {
+	for vin, expected := range map[string]byte{"1M8GDM9AXKP042788": 'X', "11111111111111111": '1', "1HGCM82633A004352": '3'} {
+		if actual := vinCheckDigit(vin); actual != expected {
+			fmt.Println("vinCheckDigit of", vin, "Expected:", string(expected), "Actual:", string(actual))
+			t.FailNow()
+		}
+	}
+}
+
+//TestVehicle_registerVehicle
+func TestVehicle_registerVehicle(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkUnauthorized(t, stub, getVehicleForTesting("registerVehicle", "100001", getVINForTesting("100001"), "US"),
+		"registerVehicle: Role workshop is not authorized - requires one of: registry")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInvoke(t, stub, getVehicleForTesting("registerVehicle", "100001", getVINForTesting("100001"), "US"))
+	res := stub.MockInvoke("1", getVehicleForTesting("registerVehicle", "100001", getVINForTesting("100002"), "US"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeAlreadyExists, "registerVehicle: This Vehicle already exists: 100001")
+	res = stub.MockInvoke("1", getVehicleForTesting("registerVehicle", "100002", getVINForTesting("100001"), "US"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeAlreadyExists,
+		"registerVehicle: VIN "+getVINForTesting("100001")+" is already registered for vehicle with ID: 100001")
+	res = stub.MockInvoke("1", [][]byte{[]byte("readVehicle"), []byte("100001")})
+	var vehicle Vehicle
+	err := json.Unmarshal(res.Payload, &vehicle)
+	if err != nil || vehicle.VIN != getVINForTesting("100001") || vehicle.Year != 2019 || vehicle.SubmitterMSPID != "Org1MSP" {
+		fmt.Println("func readVehicle failed", res.Message, string(res.Payload))
+		t.FailNow()
+	}
+	res = stub.MockInvoke("1", [][]byte{[]byte("readVehicle"), []byte("100002")})
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeVehicleNotRegistered, "readVehicle: No vehicle registered with ID: 100002")
+}
+
+//TestVehicle_invalidVehicles
+func TestVehicle_invalidVehicles(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	checkInit(t, stub, getInitForTesting(""))
+	tests := []struct {
+		input       []byte
+		expectedErr string
+	}{
+		{getVehicleForTesting("registerVehicle", "100001", "1M8GDM9AXKP04278", "US")[1], "vin: must be 17 characters of 0-9 and A-Z except I, O and Q"},
+		{getVehicleForTesting("registerVehicle", "100001", "1M8GDM9AIKP042788", "US")[1], "vin: must be 17 characters of 0-9 and A-Z except I, O and Q"},
+		{getVehicleForTesting("registerVehicle", "100001", "1M8GDM9A1KP042788", "DE")[1], "vin: check digit 1 in position 9 does not match, expected X"},
+		{getVehicleForTesting("registerVehicle", "100001", "WVWZZZ1JZ3W386752", "US")[1], "vin: check digit Z in position 9 does not match, expected 9"},
+		{[]byte("{\"vehicleID\":\"100001\",\"docType\":\"Asset.Vehicle\",\"vin\":\"1M8GDM9AXKP042788\",\"make\":\"\",\"model\":\"Focus\"," +
+			"\"year\":1980.5,\"registrationCountry\":\"usa\"}"),
+			"make: must be 1 to 64 characters; year: must be a year from 1981 to 2100; " +
+				"registrationCountry: must be an ISO 3166-1 alpha-2 country code, e.g. DE"},
+	}
+	for _, test := range tests {
+		res := stub.MockInvoke("1", [][]byte{[]byte("registerVehicle"), test.input})
+		checkErrorResponse(t, res.Status, res.Message, BADREQUEST, codeValidationFailed, "registerVehicle: Vehicle Data is Corrupted: "+test.expectedErr)
+	}
+	checkInvoke(t, stub, getVehicleForTesting("registerVehicle", "100001", "WVWZZZ1JZ3W386752", "DE"))
+}
+
+//TestVehicle_updateVehicle
+func TestVehicle_updateVehicle(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	checkInit(t, stub, getInitForTesting(""))
+	checkRegisterVehicles(t, stub, "100001", "100002")
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	res := stub.MockInvoke("1", getVehicleForTesting("updateVehicle", "100003", getVINForTesting("100003"), "US"))
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeVehicleNotRegistered, "updateVehicle: No vehicle registered with ID: 100003")
+	res = stub.MockInvoke("1", getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100002"), "US"))
+	checkErrorResponse(t, res.Status, res.Message, CONFLICT, codeAlreadyExists,
+		"updateVehicle: VIN "+getVINForTesting("100002")+" is already registered for vehicle with ID: 100002")
+	checkInvoke(t, stub, getVehicleForTesting("updateVehicle", "100001", getVINForTesting("100003"), "CA"))
+	checkInvoke(t, stub, getVehicleForTesting("registerVehicle", "100004", getVINForTesting("100001"), "US"))
+}
+
+//TestVehicle_addNewReadingUnregistered
+func TestVehicle_addNewReadingUnregistered(t *testing.T) {
+	reading := new(ReadingAsset)
+	stub := NewExtendedMockStub("reading", reading)
+	stub.Creator = getCreatorForTesting("Org1MSP", "workshop")
+	checkInit(t, stub, getInitForTesting(""))
+	res := stub.MockInvoke("1", getFirstReadingAssetForTesting())
+	checkErrorResponse(t, res.Status, res.Message, NOTFOUND, codeVehicleNotRegistered, "addNewReading: No vehicle registered with ID: 100001")
+	checkRegisterVehicles(t, stub, "100001")
+	checkInvoke(t, stub, getFirstReadingAssetForTesting())
+}
+
+//checkRegisterVehicles - helper registering vehicles with VINs derived from their IDs, as the registry
+func checkRegisterVehicles(t *testing.T, stub *ExtendedMockStub, vehicleIDs ...string) {
+	creator := stub.Creator
+	stub.Creator = getCreatorForTesting("Org1MSP", "registry")
+	for _, vehicleID := range vehicleIDs {
+		checkInvoke(t, stub, getVehicleForTesting("registerVehicle", vehicleID, getVINForTesting(vehicleID), "US"))
+	}
+	stub.Creator = creator
+}
+
+//Get a Vehicle JSON for testing
+func getVehicleForTesting(function string, vehicleID string, vin string, country string) [][]byte {
+	return [][]byte{[]byte(function),
+		[]byte("{\"vehicleID\":\"" + vehicleID + "\",\"docType\":\"Asset.Vehicle\",\"vin\":\"" + vin +
+			"\",\"make\":\"Ford\",\"model\":\"Focus\",\"year\":2019,\"registrationCountry\":\"" + country + "\"}")}
+}
+
+//Get a valid North American VIN with the last four digits of the vehicle ID as serial number for testing
+func getVINForTesting(vehicleID string) string {
+	vin := []byte("1M8GDM9A0KP04" + vehicleID[len(vehicleID)-4:])
+	vin[8] = vinCheckDigit(string(vin))
+	return string(vin)
+}